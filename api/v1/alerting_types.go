@@ -0,0 +1,186 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PayloadSource is the payload of a Grafana resource, given inline or produced by a generator.
+type PayloadSource struct {
+	// Payload is the resource, as expected by the Grafana provisioning API.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Payload *runtime.RawExtension `json:"payload,omitempty"`
+
+	// Generator produces the resource, as expected by the Grafana provisioning API.
+	// +optional
+	Generator string `json:"generator,omitempty"`
+
+	// Config of the generator.
+	// +optional
+	Config string `json:"config,omitempty"`
+
+	// ConfigFrom are YAML configs merged in order, then with the inline config. Later configs override the values of earlier ones.
+	// +optional
+	ConfigFrom []ConfigSource `json:"configFrom,omitempty"`
+
+	// ImagePullSecrets are the secrets holding the credentials of the registry serving the generator.
+	// They must be of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, and live in the namespace of the resource.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// ContactPointSpec defines the desired state of ContactPoint
+// +kubebuilder:validation:XValidation:rule="has(self.payload) != has(self.generator)",message="exactly one of payload or generator must be set"
+type ContactPointSpec struct {
+	PayloadSource `json:",inline"`
+
+	// GrafanaInstance is the name of the GrafanaInstance holding the contact point, in the namespace of the contact point.
+	// The Grafana of the controller is used if not set.
+	// +optional
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+}
+
+// AlertingConditionReady is true when a contact point or a notification policy is applied to Grafana.
+const AlertingConditionReady = "Ready"
+
+// ContactPointStatus defines the observed state of ContactPoint
+type ContactPointStatus struct {
+	// UID of the contact point in Grafana.
+	UID string `json:"uid,omitempty"`
+	// Name of the contact point in Grafana.
+	Name string `json:"name,omitempty"`
+	// Type of the contact point, eg: email or slack.
+	Type string `json:"type,omitempty"`
+	// GrafanaInstance is the name of the GrafanaInstance the contact point was applied to, empty for the Grafana of the controller.
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+	// GeneratorDigest is the digest the generator reference resolved to when it was last pulled from a registry.
+	GeneratorDigest string `json:"generatorDigest,omitempty"`
+	// ObservedGeneration is the generation of the contact point last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the contact point state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.status.name`
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.status.type`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.uid`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ContactPoint is the Schema for the contactpoints API
+type ContactPoint struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ContactPointSpec   `json:"spec,omitempty"`
+	Status ContactPointStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the contact point.
+func (c *ContactPoint) StatusConditions() *[]metav1.Condition {
+	return &c.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the contact point last reconciled.
+func (c *ContactPoint) SetObservedGeneration(generation int64) {
+	c.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// ContactPointList contains a list of ContactPoint
+type ContactPointList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ContactPoint `json:"items"`
+}
+
+// NotificationPolicySpec defines the desired state of NotificationPolicy
+// +kubebuilder:validation:XValidation:rule="has(self.payload) != has(self.generator)",message="exactly one of payload or generator must be set"
+type NotificationPolicySpec struct {
+	PayloadSource `json:",inline"`
+
+	// GrafanaInstance is the name of the GrafanaInstance holding the policy tree, in the namespace of the policy.
+	// The Grafana of the controller is used if not set.
+	// +optional
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+}
+
+// NotificationPolicyStatus defines the observed state of NotificationPolicy
+type NotificationPolicyStatus struct {
+	// Receiver is the default contact point of the applied policy tree, empty if the tree is not applied.
+	Receiver string `json:"receiver,omitempty"`
+	// GrafanaInstance is the name of the GrafanaInstance the policy tree was applied to, empty for the Grafana of the controller.
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+	// GeneratorDigest is the digest the generator reference resolved to when it was last pulled from a registry.
+	GeneratorDigest string `json:"generatorDigest,omitempty"`
+	// ObservedGeneration is the generation of the policy last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the policy state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.grafanaInstance`
+//+kubebuilder:printcolumn:name="Receiver",type=string,JSONPath=`.status.receiver`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// NotificationPolicy is the Schema for the notificationpolicies API.
+// It replaces the whole policy tree of a Grafana organization, so only one policy may target a Grafana instance.
+type NotificationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotificationPolicySpec   `json:"spec,omitempty"`
+	Status NotificationPolicyStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the policy.
+func (n *NotificationPolicy) StatusConditions() *[]metav1.Condition {
+	return &n.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the policy last reconciled.
+func (n *NotificationPolicy) SetObservedGeneration(generation int64) {
+	n.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// NotificationPolicyList contains a list of NotificationPolicy
+type NotificationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ContactPoint{}, &ContactPointList{}, &NotificationPolicy{}, &NotificationPolicyList{})
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AlertRuleGroupSpec defines the desired state of AlertRuleGroup
+type AlertRuleGroupSpec struct {
+	// Generator outputs the rule group, as expected by the Grafana provisioning API. It must set the title and the folderUid of the group.
+	// +kubebuilder:validation:required
+	Generator string `json:"generator,omitempty"`
+
+	// +kubebuilder:validation:required
+	Config string `json:"config,omitempty"`
+
+	// ConfigFrom are YAML configs merged in order, then with the inline config. Later configs override the values of earlier ones.
+	// +optional
+	ConfigFrom []ConfigSource `json:"configFrom,omitempty"`
+
+	// ImagePullSecrets are the secrets holding the credentials of the registry serving the generator.
+	// They must be of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, and live in the namespace of the rule group.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// GrafanaInstance is the name of the GrafanaInstance holding the rule group, in the namespace of the rule group.
+	// The Grafana of the controller is used if not set.
+	// +optional
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+}
+
+// AlertRuleGroupConditionReady is true when the generated rule group is applied to Grafana.
+const AlertRuleGroupConditionReady = "Ready"
+
+// AlertRuleGroupStatus defines the observed state of AlertRuleGroup
+type AlertRuleGroupStatus struct {
+	// FolderUID is the UID of the folder holding the rule group in Grafana.
+	FolderUID string `json:"folderUID,omitempty"`
+	// Title of the rule group in Grafana.
+	Title string `json:"title,omitempty"`
+	// GrafanaInstance is the name of the GrafanaInstance holding the rule group, empty for the Grafana of the controller.
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+	// Rules is the amount of rules in the group.
+	Rules int `json:"rules,omitempty"`
+	// GeneratorDigest is the digest the generator reference resolved to when it was last pulled from a registry.
+	GeneratorDigest string `json:"generatorDigest,omitempty"`
+	// ObservedGeneration is the generation of the rule group last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the rule group state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Generator",type=string,JSONPath=`.spec.generator`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Folder",type=string,JSONPath=`.status.folderUID`
+//+kubebuilder:printcolumn:name="Title",type=string,JSONPath=`.status.title`
+//+kubebuilder:printcolumn:name="Rules",type=integer,JSONPath=`.status.rules`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// AlertRuleGroup is the Schema for the alertrulegroups API
+type AlertRuleGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AlertRuleGroupSpec   `json:"spec,omitempty"`
+	Status AlertRuleGroupStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the rule group.
+func (a *AlertRuleGroup) StatusConditions() *[]metav1.Condition {
+	return &a.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the rule group last reconciled.
+func (a *AlertRuleGroup) SetObservedGeneration(generation int64) {
+	a.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// AlertRuleGroupList contains a list of AlertRuleGroup
+type AlertRuleGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertRuleGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertRuleGroup{}, &AlertRuleGroupList{})
+}
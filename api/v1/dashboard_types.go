@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -27,6 +28,62 @@ type DashboardSpec struct {
 
 	// +kubebuilder:validation:required
 	Config string `json:"config,omitempty"`
+
+	// ConfigFrom are YAML configs merged in order, then with the inline config. Later configs override the values of earlier ones.
+	// +optional
+	ConfigFrom []ConfigSource `json:"configFrom,omitempty"`
+
+	// ImagePullSecrets are the secrets holding the credentials of the registry serving the generator.
+	// They must be of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, and live in the namespace of the dashboard.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Folder is the Grafana folder holding the dashboard, the General folder if not set.
+	// +optional
+	Folder *FolderReference `json:"folder,omitempty"`
+
+	// Grafana selects the GrafanaInstances the dashboard is applied to, the Grafana of the controller if not set.
+	// +optional
+	Grafana *GrafanaInstanceReference `json:"grafana,omitempty"`
+}
+
+// GrafanaInstanceReference selects GrafanaInstances in the namespace of the dashboard, by name or by labels.
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.selector)",message="exactly one of name or selector must be set"
+type GrafanaInstanceReference struct {
+	// Name of a GrafanaInstance.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Selector selects GrafanaInstances by labels.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ConfigSource references a key of a ConfigMap or of a Secret holding YAML config, in the namespace of the dashboard.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapKeyRef) != has(self.secretKeyRef)",message="exactly one of configMapKeyRef or secretKeyRef must be set"
+type ConfigSource struct {
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// FolderReference references a Grafana folder by UID, by title, or through a GrafanaFolder resource.
+// +kubebuilder:validation:XValidation:rule="has(self.uid) || has(self.title) || has(self.grafanaFolder)",message="uid, title or grafanaFolder must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.grafanaFolder) || !(has(self.uid) || has(self.title) || has(self.create))",message="grafanaFolder excludes uid, title and create"
+// +kubebuilder:validation:XValidation:rule="!has(self.create) || !self.create || has(self.title)",message="title is required to create the folder"
+type FolderReference struct {
+	// GrafanaFolder is the name of a GrafanaFolder resource in the namespace of the dashboard.
+	// +optional
+	GrafanaFolder string `json:"grafanaFolder,omitempty"`
+	// UID of the folder.
+	// +optional
+	UID string `json:"uid,omitempty"`
+	// Title of the folder. If no UID is given, the folder is looked up by title among the top level folders.
+	// +optional
+	Title string `json:"title,omitempty"`
+	// Create creates the folder if it does not exist, with the given UID if any.
+	// +optional
+	Create bool `json:"create,omitempty"`
 }
 
 const (
@@ -40,6 +97,59 @@ type DashboardStatus struct {
 	SyncStatus string      `json:"syncStatus,omitempty"`
 	Grafana    GrafanaInfo `json:"grafana,omitempty"`
 	Error      string      `json:"error,omitempty"`
+	// ErrorField is the path of the config field the generator reported as invalid.
+	ErrorField string `json:"errorField,omitempty"`
+	// Resources are the Grafana resources generated alongside the dashboard.
+	Resources []ManagedResource `json:"resources,omitempty"`
+	// GeneratorDigest is the digest the generator reference resolved to when it was last pulled from a registry.
+	GeneratorDigest string `json:"generatorDigest,omitempty"`
+	// PayloadChecksum is the checksum of the dashboard last applied to Grafana.
+	PayloadChecksum string `json:"payloadChecksum,omitempty"`
+	// ObservedGeneration is the generation of the dashboard last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the dashboard state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Instances are the states of the dashboard in the GrafanaInstances it is applied to.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Instances []DashboardInstanceStatus `json:"instances,omitempty"`
+}
+
+// DashboardInstanceStatus is the state of a dashboard in a GrafanaInstance.
+type DashboardInstanceStatus struct {
+	// Name of the GrafanaInstance.
+	Name    string      `json:"name"`
+	Grafana GrafanaInfo `json:"grafana,omitempty"`
+	// Resources are the Grafana resources generated alongside the dashboard.
+	Resources []ManagedResource `json:"resources,omitempty"`
+	// PayloadChecksum is the checksum of the dashboard last applied to the instance.
+	PayloadChecksum string `json:"payloadChecksum,omitempty"`
+}
+
+// Types of the dashboard conditions.
+const (
+	// DashboardConditionGeneratorFetched is true when the generator has been loaded.
+	DashboardConditionGeneratorFetched = "GeneratorFetched"
+	// DashboardConditionGenerated is true when the generator ran successfully with the dashboard config.
+	DashboardConditionGenerated = "Generated"
+	// DashboardConditionSynced is true when the generated dashboard and resources have been applied to Grafana.
+	DashboardConditionSynced = "Synced"
+	// DashboardConditionReady is true when all the reconciliation steps succeeded.
+	DashboardConditionReady = "Ready"
+	// DashboardConditionDrifted is true when the dashboard has been modified or deleted in Grafana since it was last applied.
+	DashboardConditionDrifted = "Drifted"
+)
+
+// ManagedResource is a Grafana resource generated alongside a dashboard.
+type ManagedResource struct {
+	Kind string `json:"kind"`
+	UID  string `json:"uid"`
+	// Created is true if the dashboard created the resource in Grafana. Folders are only deleted by the dashboard that created them.
+	Created bool `json:"created,omitempty"`
 }
 
 type GrafanaInfo struct {
@@ -48,11 +158,14 @@ type GrafanaInfo struct {
 	Version int    `json:"version,omitempty"`
 	URL     string `json:"url,omitempty"`
 	Slug    string `json:"slug,omitempty"`
+	// FolderUID is the UID of the folder holding the dashboard, empty for the General folder.
+	FolderUID string `json:"folderUID,omitempty"`
 }
 
 //+kubebuilder:printcolumn:name="Generator",type=string,JSONPath=`.spec.generator`
 //+kubebuilder:printcolumn:name="Sync Status",type=string,JSONPath=`.status.syncStatus`
-//+kubebuilder:printcolumn:name="Error",type=string,JSONPath=`.status.errorurl`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Error",type=string,JSONPath=`.status.error`
 //+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.grafana.uid`
 //+kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.status.grafana.url`
 //+kubebuilder:object:root=true
@@ -0,0 +1,153 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GrafanaDatasourceSpec defines the desired state of GrafanaDatasource
+type GrafanaDatasourceSpec struct {
+	// UID of the datasource in Grafana, the UID of the GrafanaDatasource resource is used if empty.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="uid is immutable"
+	// +optional
+	UID string `json:"uid,omitempty"`
+
+	// Name of the datasource in Grafana.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Type of the datasource, eg: prometheus or loki.
+	// +kubebuilder:validation:MinLength=1
+	Type string `json:"type"`
+
+	// Access tells if Grafana proxies the requests to the datasource, or if the browser sends them directly.
+	// +kubebuilder:validation:Enum=proxy;direct
+	// +kubebuilder:default=proxy
+	// +optional
+	Access string `json:"access,omitempty"`
+
+	// URL of the datasource.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Database of the datasource.
+	// +optional
+	Database string `json:"database,omitempty"`
+
+	// User of the datasource.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// BasicAuth enables basic authentication, the password is set with the basicAuthPassword secure field.
+	// +optional
+	BasicAuth bool `json:"basicAuth,omitempty"`
+
+	// BasicAuthUser is the user of basic authentication.
+	// +optional
+	BasicAuthUser string `json:"basicAuthUser,omitempty"`
+
+	// IsDefault makes the datasource the default one of the organization.
+	// +optional
+	IsDefault bool `json:"isDefault,omitempty"`
+
+	// JSONData is the settings of the datasource, specific to its type.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	JSONData *runtime.RawExtension `json:"jsonData,omitempty"`
+
+	// SecureJSONData are the secret settings of the datasource, read from Secrets of the namespace of the datasource.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	SecureJSONData []SecureJSONField `json:"secureJsonData,omitempty"`
+
+	// GrafanaInstance is the name of the GrafanaInstance holding the datasource, in the namespace of the datasource.
+	// The Grafana of the controller is used if not set.
+	// +optional
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+}
+
+// SecureJSONField is a secret setting of a datasource.
+type SecureJSONField struct {
+	// Name of the setting, eg: basicAuthPassword or httpHeaderValue1.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// SecretKeyRef is the Secret key holding the value of the setting.
+	SecretKeyRef corev1.SecretKeySelector `json:"secretKeyRef"`
+}
+
+// GrafanaDatasourceConditionReady is true when the datasource is applied to Grafana.
+const GrafanaDatasourceConditionReady = "Ready"
+
+// GrafanaDatasourceStatus defines the observed state of GrafanaDatasource
+type GrafanaDatasourceStatus struct {
+	// UID of the datasource in Grafana, dashboards reference the datasource with it.
+	UID string `json:"uid,omitempty"`
+	// ID of the datasource in Grafana.
+	ID int64 `json:"id,omitempty"`
+	// GrafanaInstance is the name of the GrafanaInstance the datasource was applied to, empty for the Grafana of the controller.
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+	// ObservedGeneration is the generation of the datasource last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the datasource state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.spec.name`
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.uid`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// GrafanaDatasource is the Schema for the grafanadatasources API
+type GrafanaDatasource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaDatasourceSpec   `json:"spec,omitempty"`
+	Status GrafanaDatasourceStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the datasource.
+func (g *GrafanaDatasource) StatusConditions() *[]metav1.Condition {
+	return &g.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the datasource last reconciled.
+func (g *GrafanaDatasource) SetObservedGeneration(generation int64) {
+	g.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaDatasourceList contains a list of GrafanaDatasource
+type GrafanaDatasourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaDatasource `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaDatasource{}, &GrafanaDatasourceList{})
+}
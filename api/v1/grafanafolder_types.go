@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GrafanaFolderSpec defines the desired state of GrafanaFolder
+type GrafanaFolderSpec struct {
+	// Title of the folder in Grafana.
+	// +kubebuilder:validation:MinLength=1
+	Title string `json:"title"`
+
+	// UID of the folder in Grafana, the UID of the GrafanaFolder resource is used if empty.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="uid is immutable"
+	// +optional
+	UID string `json:"uid,omitempty"`
+
+	// Permissions of the folder. When set, they replace all the permissions of the folder in Grafana.
+	// +optional
+	Permissions []FolderPermission `json:"permissions,omitempty"`
+}
+
+// FolderPermission grants a permission on a folder to a role, a team or a user.
+// +kubebuilder:validation:XValidation:rule="[has(self.role), has(self.team), has(self.user)].filter(x, x).size() == 1",message="exactly one of role, team or user must be set"
+type FolderPermission struct {
+	// Role is a basic Grafana role.
+	// +kubebuilder:validation:Enum=Viewer;Editor;Admin
+	// +optional
+	Role string `json:"role,omitempty"`
+	// Team is the name of a Grafana team.
+	// +optional
+	Team string `json:"team,omitempty"`
+	// User is the login or the email of a Grafana user.
+	// +optional
+	User string `json:"user,omitempty"`
+	// Permission is the permission granted.
+	// +kubebuilder:validation:Enum=View;Edit;Admin
+	Permission string `json:"permission"`
+}
+
+// GrafanaFolderConditionReady is true when the folder and its permissions are applied to Grafana.
+const GrafanaFolderConditionReady = "Ready"
+
+// GrafanaFolderStatus defines the observed state of GrafanaFolder
+type GrafanaFolderStatus struct {
+	// UID of the folder in Grafana.
+	UID string `json:"uid,omitempty"`
+	// URL of the folder in Grafana.
+	URL string `json:"url,omitempty"`
+	// PermissionsApplied is true if the permissions of the spec replaced the ones of the folder in Grafana.
+	// Once removed from the spec, the folder gets back the permissions Grafana grants by default.
+	PermissionsApplied bool `json:"permissionsApplied,omitempty"`
+	// ObservedGeneration is the generation of the folder last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the folder state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Title",type=string,JSONPath=`.spec.title`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.uid`
+//+kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.status.url`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// GrafanaFolder is the Schema for the grafanafolders API.
+// Deleting it deletes the folder in Grafana along with its dashboards and alert rules,
+// so the deletion waits until no Dashboard nor AlertRuleGroup uses the folder.
+type GrafanaFolder struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GrafanaFolderSpec   `json:"spec,omitempty"`
+	Status GrafanaFolderStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the folder.
+func (g *GrafanaFolder) StatusConditions() *[]metav1.Condition {
+	return &g.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the folder last reconciled.
+func (g *GrafanaFolder) SetObservedGeneration(generation int64) {
+	g.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaFolderList contains a list of GrafanaFolder
+type GrafanaFolderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaFolder `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaFolder{}, &GrafanaFolderList{})
+}
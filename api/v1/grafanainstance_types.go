@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GrafanaInstanceSpec defines the desired state of GrafanaInstance
+type GrafanaInstanceSpec struct {
+	// URL of the Grafana server.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// OrgID is the organization dashboards are applied to, the current organization of the token user if not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	OrgID int64 `json:"orgID,omitempty"`
+
+	// TokenSecretRef references the key of a Secret holding a service account token, in the namespace of the instance.
+	TokenSecretRef corev1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+//+kubebuilder:printcolumn:name="Org",type=integer,JSONPath=`.spec.orgID`
+//+kubebuilder:object:root=true
+
+// GrafanaInstance is the Schema for the grafanainstances API
+type GrafanaInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GrafanaInstanceSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GrafanaInstanceList contains a list of GrafanaInstance
+type GrafanaInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GrafanaInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GrafanaInstance{}, &GrafanaInstanceList{})
+}
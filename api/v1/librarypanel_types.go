@@ -0,0 +1,94 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LibraryPanelSpec defines the desired state of LibraryPanel
+// +kubebuilder:validation:XValidation:rule="has(self.payload) != has(self.generator)",message="exactly one of payload or generator must be set"
+type LibraryPanelSpec struct {
+	PayloadSource `json:",inline"`
+
+	// GrafanaInstance is the name of the GrafanaInstance holding the library panel, in the namespace of the library panel.
+	// The Grafana of the controller is used if not set.
+	// +optional
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+}
+
+// LibraryPanelConditionReady is true when the library panel is applied to Grafana.
+const LibraryPanelConditionReady = "Ready"
+
+// LibraryPanelStatus defines the observed state of LibraryPanel
+type LibraryPanelStatus struct {
+	// UID of the library panel in Grafana, dashboards reference the panel with it.
+	UID string `json:"uid,omitempty"`
+	// Name of the library panel in Grafana.
+	Name string `json:"name,omitempty"`
+	// FolderUID is the UID of the folder holding the library panel, empty for the General folder.
+	FolderUID string `json:"folderUID,omitempty"`
+	// GrafanaInstance is the name of the GrafanaInstance the library panel was applied to, empty for the Grafana of the controller.
+	GrafanaInstance string `json:"grafanaInstance,omitempty"`
+	// GeneratorDigest is the digest the generator reference resolved to when it was last pulled from a registry.
+	GeneratorDigest string `json:"generatorDigest,omitempty"`
+	// ObservedGeneration is the generation of the library panel last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions report the latest observations of the library panel state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.status.name`
+//+kubebuilder:printcolumn:name="UID",type=string,JSONPath=`.status.uid`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// LibraryPanel is the Schema for the librarypanels API
+type LibraryPanel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LibraryPanelSpec   `json:"spec,omitempty"`
+	Status LibraryPanelStatus `json:"status,omitempty"`
+}
+
+// StatusConditions returns the conditions reporting the state of the library panel.
+func (l *LibraryPanel) StatusConditions() *[]metav1.Condition {
+	return &l.Status.Conditions
+}
+
+// SetObservedGeneration records the generation of the library panel last reconciled.
+func (l *LibraryPanel) SetObservedGeneration(generation int64) {
+	l.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// LibraryPanelList contains a list of LibraryPanel
+type LibraryPanelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LibraryPanel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LibraryPanel{}, &LibraryPanelList{})
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/jlevesy/dawg/gdk"
 	"github.com/jlevesy/dawg/generator"
 	"github.com/jlevesy/dawg/pkg/grafana"
 )
@@ -19,16 +23,31 @@ func main() {
 
 func run() int {
 	var (
-		generatorURL string
-		configPath   string
-		grafanaURL   string
-		grafanaToken string
+		generatorURL     string
+		dockerConfigPath string
+		registriesPath   string
+		configPath       string
+		grafanaURL       string
+		grafanaToken     string
+		verbose          bool
+
+		instantiateTimeout time.Duration
+		executeTimeout     time.Duration
+		maxMemoryPages     uint
+		maxOutputSize      uint
 	)
 
 	flag.StringVar(&generatorURL, "generator", "", "Path to the WASM binary of the generator")
+	flag.StringVar(&dockerConfigPath, "docker-config", generator.DefaultDockerConfigPath(), "Path to the docker config file holding the registry credentials")
+	flag.StringVar(&registriesPath, "registries-config", "", "Path to a YAML file configuring how to reach the generator registries")
 	flag.StringVar(&configPath, "config", "", "Path to the config of the generator")
 	flag.StringVar(&grafanaURL, "grafana-url", "", "URL of the grafana instance to provision")
 	flag.StringVar(&grafanaToken, "grafana-token", "", "API token to use with the grafana instance")
+	flag.BoolVar(&verbose, "verbose", false, "Print what the generator wrote to its stdout and stderr")
+	flag.DurationVar(&instantiateTimeout, "instantiate-timeout", time.Second, "Maximum duration allowed to instantiate the generator")
+	flag.DurationVar(&executeTimeout, "execute-timeout", time.Second, "Maximum duration allowed for the generator to run")
+	flag.UintVar(&maxMemoryPages, "max-memory-pages", 0, "Maximum amount of 64KiB memory pages the generator can use, 0 means no limit")
+	flag.UintVar(&maxOutputSize, "max-output-size", 0, "Maximum total size in bytes of the generator output and resources, 0 means no limit")
 	flag.Parse()
 
 	if generatorURL == "" || configPath == "" {
@@ -41,16 +60,50 @@ func run() int {
 		return 1
 	}
 
+	if maxMemoryPages > generator.MaxMemoryPages {
+		fmt.Printf("Max memory pages can't exceed %d\n", generator.MaxMemoryPages)
+		return 1
+	}
+
+	if maxOutputSize > math.MaxUint32 {
+		fmt.Printf("Max output size can't exceed %d bytes\n", uint64(math.MaxUint32))
+		return 1
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	store, err := generator.DefaultStore()
+	dockerConfig, err := generator.LoadDockerConfig(dockerConfigPath)
+	if err != nil {
+		fmt.Println("could not load docker config", err)
+		return 1
+	}
+
+	storeOpts := []generator.StoreOpt{generator.WithRegistryCredentials(dockerConfig.Credential)}
+
+	if registriesPath != "" {
+		registriesConfig, err := generator.LoadRegistriesConfig(registriesPath)
+		if err != nil {
+			fmt.Println("could not load registries config", err)
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithRegistriesConfig(registriesConfig))
+	}
+
+	store, err := generator.DefaultStore(storeOpts...)
 	if err != nil {
 		fmt.Println("could not build default generator stores", err)
 		return 1
 	}
 
-	runtime, shutdownRuntime, err := generator.DefaultRuntime(ctx)
+	runtime, shutdownRuntime, err := generator.DefaultRuntime(
+		ctx,
+		generator.WithInstantiateTimeout(instantiateTimeout),
+		generator.WithExecuteTimeout(executeTimeout),
+		generator.WithMaxMemoryPages(uint32(maxMemoryPages)),
+		generator.WithMaxOutputSize(uint32(maxOutputSize)),
+	)
 	if err != nil {
 		fmt.Println("could not setup generator runtime", err)
 		return 1
@@ -83,10 +136,49 @@ func run() int {
 
 	dashboardPayload, err := runtime.Execute(ctx, gen, configBytes)
 	if err != nil {
+		var (
+			execErr   *generator.ExecutionError
+			configErr *generator.ConfigValidationError
+		)
+
+		if verbose && errors.As(err, &execErr) {
+			printGeneratorOutput(execErr.Logs, execErr.Stdout, execErr.Stderr)
+		}
+
+		if errors.As(err, &configErr) {
+			fmt.Println("config", configPath, "does not match the generator schema:")
+
+			for _, violation := range configErr.Violations {
+				fmt.Println("  -", violation)
+			}
+
+			return 1
+		}
+
 		fmt.Println(err)
 		return 1
 	}
 
+	if verbose {
+		printGeneratorOutput(dashboardPayload.Logs, dashboardPayload.Stdout, dashboardPayload.Stderr)
+	}
+
+	var dashboardJSON []byte
+
+	for _, res := range dashboardPayload.AllResources() {
+		if res.Kind != gdk.ResourceKindDashboard {
+			fmt.Println("Skipping generated resource of kind", res.Kind)
+			continue
+		}
+
+		dashboardJSON = res.Payload
+	}
+
+	if dashboardJSON == nil {
+		fmt.Println("generator did not produce a dashboard")
+		return 1
+	}
+
 	var grafanaOpts []grafana.ClientOpt
 
 	if grafanaToken != "" {
@@ -98,7 +190,7 @@ func run() int {
 	dashboard, err := grafanaClient.CreateDashboard(
 		ctx,
 		&grafana.CreateDashboardRequest{
-			Dashboard: dashboardPayload.Payload,
+			Dashboard: dashboardJSON,
 			Overwrite: true,
 		},
 	)
@@ -111,3 +203,17 @@ func run() int {
 
 	return 0
 }
+
+func printGeneratorOutput(logs []generator.LogEntry, stdout, stderr []byte) {
+	for _, entry := range logs {
+		fmt.Printf("Generator log [%s] %s\n", entry.Level, entry.Message)
+	}
+
+	if len(stdout) > 0 {
+		fmt.Printf("Generator stdout:\n%s\n", stdout)
+	}
+
+	if len(stderr) > 0 {
+		fmt.Printf("Generator stderr:\n%s\n", stderr)
+	}
+}
@@ -3,19 +3,24 @@ package main
 import (
 	"context"
 	"flag"
+	"math"
 	"os"
+	"strings"
+	"time"
 
 	dawgv1 "github.com/jlevesy/dawg/api/v1"
 	"github.com/jlevesy/dawg/generator"
 	"github.com/jlevesy/dawg/internal/controller"
 	"github.com/jlevesy/dawg/pkg/grafana"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 var (
@@ -38,12 +43,48 @@ func run() int {
 		probeAddr            string
 		grafanaURL           string
 		grafanaToken         string
+		moduleCacheSize      int
+		compilationCacheDir  string
+		instantiateTimeout   time.Duration
+		executeTimeout       time.Duration
+		maxMemoryPages       uint
+		maxOutputSize        uint
+		enableWebhook        bool
+		webhookPort          int
+		webhookCertDir       string
+		webhookDryRun        bool
+		registryPullSecret   string
+		registriesPath       string
+		registryCacheDir     string
+		registryCacheMaxSize int64
+		requirePinned        bool
+		verificationKeysPath string
+		resyncInterval       time.Duration
+		grafanaContext       bool
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.StringVar(&grafanaURL, "grafana-url", "", "URL of the managed grafana server")
+	flag.StringVar(&grafanaURL, "grafana-url", "", "URL of the default grafana server, Dashboards must reference GrafanaInstances if empty")
 	flag.StringVar(&grafanaToken, "grafana-token", "", "Auth token for the grafana server")
+	flag.IntVar(&moduleCacheSize, "generator-cache-size", 32, "Maximum amount of compiled generators kept in memory, 0 disables the cache")
+	flag.StringVar(&compilationCacheDir, "generator-cache-dir", "", "Directory where compiled generators are persisted, disabled if empty")
+	flag.DurationVar(&instantiateTimeout, "generator-instantiate-timeout", time.Second, "Maximum duration allowed to instantiate a generator")
+	flag.DurationVar(&executeTimeout, "generator-execute-timeout", time.Second, "Maximum duration allowed for a generator to run")
+	flag.UintVar(&maxMemoryPages, "generator-max-memory-pages", 0, "Maximum amount of 64KiB memory pages a generator can use, 0 means no limit")
+	flag.UintVar(&maxOutputSize, "generator-max-output-size", 0, "Maximum total size in bytes of a generator output and resources, 0 means no limit")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false, "Serve the Dashboard validating admission webhook")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the admission webhook server binds to")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "Directory holding the tls.crt and tls.key of the admission webhook server, defaults to the controller-runtime location")
+	flag.BoolVar(&webhookDryRun, "webhook-dry-run", false, "Reject Dashboards whose generator fails to run with their config")
+	flag.StringVar(&registryPullSecret, "registry-pull-secret", "", "Secret holding the credentials of the generator registries for all Dashboards, formatted as namespace/name")
+	flag.StringVar(&registriesPath, "registries-config", "", "Path to a YAML file configuring how to reach the generator registries")
+	flag.StringVar(&registryCacheDir, "generator-registry-cache-dir", "", "Directory where generators pulled from registries are cached, generators are pulled on each load if empty")
+	flag.Int64Var(&registryCacheMaxSize, "generator-registry-cache-max-size", 1<<30, "Maximum size in bytes of the generator registry cache, least recently used generators are deleted past it")
+	flag.BoolVar(&requirePinned, "require-pinned-generators", false, "Refuse Dashboards referencing a registry generator by tag instead of digest")
+	flag.DurationVar(&resyncInterval, "resync-interval", 10*time.Minute, "Interval at which applied Dashboards are checked for changes made in Grafana, 0 disables it")
+	flag.BoolVar(&grafanaContext, "generator-grafana-context", true, "Give dashboard generators the version and datasources of the Grafana the dashboard is applied to")
+	flag.StringVar(&verificationKeysPath, "generator-verification-keys", "", "Path to a bundle of PEM public keys, generators must be signed by one of them if set")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -63,18 +104,47 @@ func run() int {
 		grafanaToken = os.Getenv("GRAFANA_TOKEN")
 	}
 
-	if grafanaURL == "" {
-		logger.Info("Please provide a Grafana URL. Exiting.")
+	if grafanaURL != "" && grafanaToken == "" {
+		logger.Info("Please provide a Grafana Token. Exiting.")
 		return 1
 	}
 
-	if grafanaToken == "" {
-		logger.Info("Please provide a Grafana Token. Exiting.")
+	if maxMemoryPages > generator.MaxMemoryPages {
+		logger.Info("Generator max memory pages can't exceed the WebAssembly maximum. Exiting.", "max", generator.MaxMemoryPages, "got", maxMemoryPages)
+		return 1
+	}
+
+	if maxOutputSize > math.MaxUint32 {
+		logger.Info("Generator max output size can't exceed its maximum. Exiting.", "max", uint64(math.MaxUint32), "got", maxOutputSize)
 		return 1
 	}
 
 	ctrl.SetLogger(logger)
 
+	var controllerOpts []controller.Option
+
+	if registryPullSecret != "" {
+		namespace, name, ok := strings.Cut(registryPullSecret, "/")
+		if !ok || namespace == "" || name == "" {
+			logger.Info("Registry pull secret must be formatted as namespace/name. Exiting.")
+			return 1
+		}
+
+		controllerOpts = append(controllerOpts, controller.WithDefaultPullSecret(types.NamespacedName{Namespace: namespace, Name: name}))
+	}
+
+	if resyncInterval > 0 {
+		controllerOpts = append(controllerOpts, controller.WithResyncInterval(resyncInterval))
+	}
+
+	if requirePinned {
+		controllerOpts = append(controllerOpts, controller.WithRequirePinnedGenerators())
+	}
+
+	if grafanaContext {
+		controllerOpts = append(controllerOpts, controller.WithGrafanaContext())
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                        scheme,
 		Metrics:                       metricsserver.Options{BindAddress: metricsAddr},
@@ -82,6 +152,10 @@ func run() int {
 		LeaderElection:                enableLeaderElection,
 		LeaderElectionID:              "c2061b9e.dawg.urcloud.cc",
 		LeaderElectionReleaseOnCancel: true,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 	})
 	if err != nil {
 		logger.Error(err, "unable to start manager")
@@ -98,13 +172,51 @@ func run() int {
 		return 1
 	}
 
-	store, err := generator.DefaultStore()
+	var storeOpts []generator.StoreOpt
+
+	if registriesPath != "" {
+		registriesConfig, err := generator.LoadRegistriesConfig(registriesPath)
+		if err != nil {
+			logger.Error(err, "could not load registries config")
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithRegistriesConfig(registriesConfig))
+	}
+
+	if registryCacheDir != "" {
+		storeOpts = append(storeOpts, generator.WithRegistryCacheDir(registryCacheDir, registryCacheMaxSize))
+	}
+
+	if verificationKeysPath != "" {
+		verificationKeys, err := generator.LoadVerificationKeys(verificationKeysPath)
+		if err != nil {
+			logger.Error(err, "could not load generator verification keys")
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithVerificationKeys(verificationKeys...))
+	}
+
+	store, err := generator.DefaultStore(storeOpts...)
 	if err != nil {
 		logger.Error(err, "could not build default generator stores")
 		return 1
 	}
 
-	runtime, shutdownRuntime, err := generator.DefaultRuntime(context.TODO())
+	runtimeOpts := []generator.RuntimeOpt{
+		generator.WithModuleCacheSize(moduleCacheSize),
+		generator.WithInstantiateTimeout(instantiateTimeout),
+		generator.WithExecuteTimeout(executeTimeout),
+		generator.WithMaxMemoryPages(uint32(maxMemoryPages)),
+		generator.WithMaxOutputSize(uint32(maxOutputSize)),
+	}
+
+	if compilationCacheDir != "" {
+		runtimeOpts = append(runtimeOpts, generator.WithCompilationCacheDir(compilationCacheDir))
+	}
+
+	runtime, shutdownRuntime, err := generator.DefaultRuntime(context.TODO(), runtimeOpts...)
 	if err != nil {
 		logger.Error(err, "could not setup generator runtime")
 		return 1
@@ -116,17 +228,88 @@ func run() int {
 		}
 	}()
 
-	grafanaClient := grafana.NewClient(grafanaURL, grafana.WithAuthToken(grafanaToken))
+	// Without a Grafana URL, Dashboards must reference GrafanaInstances.
+	var grafanaClient *grafana.Client
+
+	if grafanaURL != "" {
+		grafanaClient = grafana.NewClient(grafanaURL, grafana.WithAuthToken(grafanaToken))
+	} else {
+		logger.Info("No Grafana URL provided, only GrafanaInstances are managed")
+	}
 
 	if err := controller.NewDashboardReconciller(
 		store,
 		runtime,
 		grafanaClient,
+		controllerOpts...,
 	).SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to set up the dashboard reconsiller")
 		return 1
 	}
 
+	if err := controller.NewContactPointReconciler(
+		store,
+		runtime,
+		grafanaClient,
+		controllerOpts...,
+	).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the contact point reconciler")
+		return 1
+	}
+
+	if err := controller.NewNotificationPolicyReconciler(
+		store,
+		runtime,
+		grafanaClient,
+		controllerOpts...,
+	).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the notification policy reconciler")
+		return 1
+	}
+
+	if err := controller.NewLibraryPanelReconciler(
+		store,
+		runtime,
+		grafanaClient,
+		controllerOpts...,
+	).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the library panel reconciler")
+		return 1
+	}
+
+	if err := controller.NewGrafanaDatasourceReconciler(grafanaClient, controllerOpts...).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the datasource reconciler")
+		return 1
+	}
+
+	if err := controller.NewAlertRuleGroupReconciler(
+		store,
+		runtime,
+		grafanaClient,
+		controllerOpts...,
+	).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the rule group reconciler")
+		return 1
+	}
+
+	// GrafanaFolders are applied to the Grafana of the controller, they report it is missing on their status otherwise.
+	if err := controller.NewGrafanaFolderReconciler(grafanaClient).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "unable to set up the folder reconciler")
+		return 1
+	}
+
+	if enableWebhook {
+		if err := controller.NewDashboardValidator(
+			store,
+			runtime,
+			webhookDryRun,
+			controllerOpts...,
+		).SetupWebhookWithManager(mgr); err != nil {
+			logger.Error(err, "unable to set up the dashboard webhook")
+			return 1
+		}
+	}
+
 	logger.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		logger.Error(err, "problem running manager")
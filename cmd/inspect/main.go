@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jlevesy/dawg/generator"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		generatorURL     string
+		dockerConfigPath string
+		registriesPath   string
+	)
+
+	flag.StringVar(&generatorURL, "generator", "", "URL of the generator to inspect")
+	flag.StringVar(&dockerConfigPath, "docker-config", generator.DefaultDockerConfigPath(), "Path to the docker config file holding the registry credentials")
+	flag.StringVar(&registriesPath, "registries-config", "", "Path to a YAML file configuring how to reach the generator registries")
+	flag.Parse()
+
+	if generatorURL == "" {
+		fmt.Println("Must provide a generator URL")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	dockerConfig, err := generator.LoadDockerConfig(dockerConfigPath)
+	if err != nil {
+		fmt.Println("could not load docker config", err)
+		return 1
+	}
+
+	storeOpts := []generator.StoreOpt{generator.WithRegistryCredentials(dockerConfig.Credential)}
+
+	if registriesPath != "" {
+		registriesConfig, err := generator.LoadRegistriesConfig(registriesPath)
+		if err != nil {
+			fmt.Println("could not load registries config", err)
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithRegistriesConfig(registriesConfig))
+	}
+
+	store, err := generator.DefaultStore(storeOpts...)
+	if err != nil {
+		fmt.Println("could not build default generator stores", err)
+		return 1
+	}
+
+	parsedGeneratorURL, err := url.Parse(generatorURL)
+	if err != nil {
+		fmt.Println("could not parse generator url", err)
+		return 1
+	}
+
+	manifest, err := generator.Inspect(ctx, store, parsedGeneratorURL)
+	if errors.Is(err, generator.ErrNoManifest) {
+		fmt.Println("generator", parsedGeneratorURL.String(), "has no manifest")
+		return 1
+	}
+	if err != nil {
+		fmt.Println("could not inspect generator", err)
+		return 1
+	}
+
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		fmt.Println("could not encode generator manifest", err)
+		return 1
+	}
+
+	fmt.Print(string(out))
+
+	return 0
+}
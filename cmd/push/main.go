@@ -10,6 +10,7 @@ import (
 	"syscall"
 
 	"github.com/jlevesy/dawg/generator"
+	"sigs.k8s.io/yaml"
 )
 
 func main() {
@@ -18,10 +19,18 @@ func main() {
 
 func run() int {
 	var (
-		generatorURL string
+		generatorURL     string
+		dockerConfigPath string
+		registriesPath   string
+		manifestPath     string
+		signingKeyPath   string
 	)
 
 	flag.StringVar(&generatorURL, "generator", "", "Path to the WASM binary of the generator")
+	flag.StringVar(&dockerConfigPath, "docker-config", generator.DefaultDockerConfigPath(), "Path to the docker config file holding the registry credentials")
+	flag.StringVar(&registriesPath, "registries-config", "", "Path to a YAML file configuring how to reach the generator registries")
+	flag.StringVar(&manifestPath, "manifest", "", "Path to a YAML or JSON manifest describing the generator")
+	flag.StringVar(&signingKeyPath, "signing-key", "", "Path to a PEM private key used to sign the generator, not signed if empty")
 	flag.Parse()
 
 	if generatorURL == "" || len(flag.Args()) == 0 {
@@ -31,7 +40,35 @@ func run() int {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	store, err := generator.DefaultStore()
+	dockerConfig, err := generator.LoadDockerConfig(dockerConfigPath)
+	if err != nil {
+		fmt.Println("could not load docker config", err)
+		return 1
+	}
+
+	storeOpts := []generator.StoreOpt{generator.WithRegistryCredentials(dockerConfig.Credential)}
+
+	if registriesPath != "" {
+		registriesConfig, err := generator.LoadRegistriesConfig(registriesPath)
+		if err != nil {
+			fmt.Println("could not load registries config", err)
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithRegistriesConfig(registriesConfig))
+	}
+
+	if signingKeyPath != "" {
+		signingKey, err := generator.LoadSigningKey(signingKeyPath)
+		if err != nil {
+			fmt.Println("could not load signing key", err)
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithSigningKey(signingKey))
+	}
+
+	store, err := generator.DefaultStore(storeOpts...)
 	if err != nil {
 		fmt.Println("could not build default generator stores", err)
 		return 1
@@ -49,7 +86,25 @@ func run() int {
 		return 1
 	}
 
-	if err := store.Store(ctx, parsedGeneratorURL, &generator.Generator{Bin: genBytes}); err != nil {
+	gen := generator.Generator{Bin: genBytes}
+
+	if manifestPath != "" {
+		manifestBytes, err := os.ReadFile(manifestPath)
+		if err != nil {
+			fmt.Println("could not read generator manifest", err)
+			return 1
+		}
+
+		var manifest generator.Manifest
+		if err := yaml.Unmarshal(manifestBytes, &manifest); err != nil {
+			fmt.Println("could not decode generator manifest", err)
+			return 1
+		}
+
+		gen.Manifest = &manifest
+	}
+
+	if err := store.Store(ctx, parsedGeneratorURL, &gen); err != nil {
 		fmt.Println("could not push generator", err)
 		return 1
 	}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jlevesy/dawg/generator"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		generatorURL     string
+		dockerConfigPath string
+		registriesPath   string
+		signingKeyPath   string
+	)
+
+	flag.StringVar(&generatorURL, "generator", "", "URL of the generator to sign, already pushed to a registry")
+	flag.StringVar(&dockerConfigPath, "docker-config", generator.DefaultDockerConfigPath(), "Path to the docker config file holding the registry credentials")
+	flag.StringVar(&registriesPath, "registries-config", "", "Path to a YAML file configuring how to reach the generator registries")
+	flag.StringVar(&signingKeyPath, "signing-key", "", "Path to a PEM private key used to sign the generator")
+	flag.Parse()
+
+	if generatorURL == "" || signingKeyPath == "" {
+		fmt.Println("Must provide a generator URL and a signing key")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	dockerConfig, err := generator.LoadDockerConfig(dockerConfigPath)
+	if err != nil {
+		fmt.Println("could not load docker config", err)
+		return 1
+	}
+
+	storeOpts := []generator.StoreOpt{generator.WithRegistryCredentials(dockerConfig.Credential)}
+
+	if registriesPath != "" {
+		registriesConfig, err := generator.LoadRegistriesConfig(registriesPath)
+		if err != nil {
+			fmt.Println("could not load registries config", err)
+			return 1
+		}
+
+		storeOpts = append(storeOpts, generator.WithRegistriesConfig(registriesConfig))
+	}
+
+	signingKey, err := generator.LoadSigningKey(signingKeyPath)
+	if err != nil {
+		fmt.Println("could not load signing key", err)
+		return 1
+	}
+
+	store, err := generator.DefaultStore(storeOpts...)
+	if err != nil {
+		fmt.Println("could not build default generator stores", err)
+		return 1
+	}
+
+	parsedGeneratorURL, err := url.Parse(generatorURL)
+	if err != nil {
+		fmt.Println("could not parse generator url", err)
+		return 1
+	}
+
+	if err := generator.Sign(ctx, store, parsedGeneratorURL, signingKey); err != nil {
+		fmt.Println("could not sign generator", err)
+		return 1
+	}
+
+	fmt.Println("Successfully signed generator", parsedGeneratorURL.String())
+
+	return 0
+}
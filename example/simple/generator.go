@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 
 	"github.com/grafana/grafana-foundation-sdk/go/common"
@@ -27,7 +28,11 @@ func generate() uint64 {
 	var cfg config
 
 	if err := yaml.Unmarshal(configBytes, &cfg); err != nil {
-		return gdk.Error(err)
+		return gdk.Error(gdk.InvalidConfigError("", err))
+	}
+
+	if cfg.AppName == "" {
+		return gdk.Error(gdk.InvalidConfigError("app_name", errors.New("must not be empty")))
 	}
 
 	dashboard, err := dashboard.NewDashboardBuilder(cfg.AppName).
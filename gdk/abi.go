@@ -0,0 +1,73 @@
+package gdk
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+// HostModule is the name of the host module exposing the dawg ABI to generators.
+const HostModule = "dawg_v1"
+
+// LogLevel is the severity of a message logged by a generator.
+type LogLevel uint32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Log sends a message to the host logger.
+func Log(level LogLevel, msg string) {
+	if msg == "" {
+		return
+	}
+
+	ptr, size := bytesToPtr([]byte(msg))
+	hostLog(uint32(level), ptr, size)
+}
+
+// SetOutput reports the main output of the generator to the host.
+func SetOutput(buf []byte) {
+	ptr, size := bytesToPtr(buf)
+	hostSetOutput(ptr, size)
+}
+
+// SetError reports a generator failure to the host.
+func SetError(err error) {
+	b, _ := json.Marshal(toRuntimeError(err))
+
+	ptr, size := bytesToPtr(b)
+	hostSetError(ptr, size)
+}
+
+// AddResource reports an additional resource of the given kind to the host.
+func AddResource(kind string, payload []byte) {
+	kindPtr, kindSize := bytesToPtr([]byte(kind))
+	ptr, size := bytesToPtr(payload)
+	hostAddResource(kindPtr, kindSize, ptr, size)
+}
+
+func bytesToPtr(buf []byte) (uint32, uint32) {
+	if len(buf) == 0 {
+		return 0, 0
+	}
+
+	return uint32(uintptr(unsafe.Pointer(&buf[0]))), uint32(len(buf))
+}
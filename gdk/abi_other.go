@@ -0,0 +1,22 @@
+//go:build !wasm
+
+package gdk
+
+// The host ABI is only available to generators compiled to WebAssembly.
+const errNoHostABI = "gdk: host ABI is only available in WebAssembly"
+
+func hostLog(_, _, _ uint32) {
+	panic(errNoHostABI)
+}
+
+func hostSetOutput(_, _ uint32) {
+	panic(errNoHostABI)
+}
+
+func hostSetError(_, _ uint32) {
+	panic(errNoHostABI)
+}
+
+func hostAddResource(_, _, _, _ uint32) {
+	panic(errNoHostABI)
+}
@@ -0,0 +1,15 @@
+//go:build wasm
+
+package gdk
+
+//go:wasmimport dawg_v1 log
+func hostLog(level, ptr, size uint32)
+
+//go:wasmimport dawg_v1 set_output
+func hostSetOutput(ptr, size uint32)
+
+//go:wasmimport dawg_v1 set_error
+func hostSetError(ptr, size uint32)
+
+//go:wasmimport dawg_v1 add_resource
+func hostAddResource(kindPtr, kindSize, ptr, size uint32)
@@ -0,0 +1,103 @@
+package gdk
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// ErrNoContext is returned by ReadContext when the host did not provide any context.
+var ErrNoContext = errors.New("gdk: no context provided by the host")
+
+// Context holds facts about the environment a generator runs for, written by the host at ContextPath.
+type Context struct {
+	// Grafana describes the Grafana server the output is applied to, nil if the host does not know it.
+	Grafana *GrafanaContext `json:"grafana,omitempty"`
+	// Resource is the Kubernetes resource the generator runs for, nil outside of the controller.
+	Resource *ResourceContext `json:"resource,omitempty"`
+}
+
+// GrafanaContext describes a Grafana server.
+type GrafanaContext struct {
+	Version     string              `json:"version"`
+	Datasources []DatasourceContext `json:"datasources"`
+}
+
+// DatasourceContext describes a datasource available in Grafana.
+type DatasourceContext struct {
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	IsDefault bool   `json:"isDefault,omitempty"`
+}
+
+// ResourceContext describes a Kubernetes resource.
+type ResourceContext struct {
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// ReadContext reads the context written by the host. It returns ErrNoContext if the host did not write any.
+func ReadContext() (*Context, error) {
+	b, err := os.ReadFile(ContextPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoContext
+		}
+
+		return nil, err
+	}
+
+	var ctx Context
+	if err := json.Unmarshal(b, &ctx); err != nil {
+		return nil, err
+	}
+
+	return &ctx, nil
+}
+
+// Datasource returns the datasource of the given type to use: the default datasource if it has this type,
+// otherwise the first datasource of this type. It returns false if Grafana has no datasource of this type.
+func (c *Context) Datasource(typ string) (DatasourceContext, bool) {
+	if c.Grafana == nil {
+		return DatasourceContext{}, false
+	}
+
+	var (
+		found DatasourceContext
+		ok    bool
+	)
+
+	for _, ds := range c.Grafana.Datasources {
+		if ds.Type != typ {
+			continue
+		}
+
+		if ds.IsDefault {
+			return ds, true
+		}
+
+		if !ok {
+			found, ok = ds, true
+		}
+	}
+
+	return found, ok
+}
+
+// DatasourceByName returns the datasource with the given name. It returns false if Grafana has no such datasource.
+func (c *Context) DatasourceByName(name string) (DatasourceContext, bool) {
+	if c.Grafana == nil {
+		return DatasourceContext{}, false
+	}
+
+	for _, ds := range c.Grafana.Datasources {
+		if ds.Name == name {
+			return ds, true
+		}
+	}
+
+	return DatasourceContext{}, false
+}
@@ -0,0 +1,37 @@
+package gdk
+
+import "encoding/json"
+
+// EnvelopeVersion identifies an output envelope.
+const EnvelopeVersion = "dawg.urcloud.cc/v1"
+
+// Kinds of resources a generator can output.
+const (
+	ResourceKindDashboard    = "dashboard"
+	ResourceKindFolder       = "folder"
+	ResourceKindAlertRule    = "alert-rule"
+	ResourceKindLibraryPanel = "library-panel"
+	// ResourceKindAlertRuleGroup is the output of generators of AlertRuleGroups.
+	ResourceKindAlertRuleGroup = "alert-rule-group"
+	// ResourceKindContactPoint is the output of generators of ContactPoints.
+	ResourceKindContactPoint = "contact-point"
+	// ResourceKindNotificationPolicy is the output of generators of NotificationPolicies.
+	ResourceKindNotificationPolicy = "notification-policy"
+)
+
+// Envelope allows a generator to output several resources at once.
+type Envelope struct {
+	Version   string             `json:"envelope"`
+	Resources []EnvelopeResource `json:"resources"`
+}
+
+// EnvelopeResource is a resource of a given kind carried by an envelope.
+type EnvelopeResource struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// MarshalEnvelope builds the output of a generator reporting several resources.
+func MarshalEnvelope(resources ...EnvelopeResource) ([]byte, error) {
+	return json.Marshal(&Envelope{Version: EnvelopeVersion, Resources: resources})
+}
@@ -0,0 +1,68 @@
+package gdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies an error reported by a generator.
+type ErrorCode string
+
+const (
+	// ErrorCodeInternal reports an unexpected failure of the generator.
+	ErrorCodeInternal ErrorCode = "internal"
+	// ErrorCodeInvalidConfig reports that the configuration given to the generator is invalid.
+	ErrorCodeInvalidConfig ErrorCode = "invalid_config"
+	// ErrorCodeUnsupportedVersion reports that the generator does not support the requested version.
+	ErrorCodeUnsupportedVersion ErrorCode = "unsupported_version"
+)
+
+// RuntimeError is the error reported by a generator to the host.
+type RuntimeError struct {
+	Err  string    `json:"err"`
+	Code ErrorCode `json:"code,omitempty"`
+	// Field is the path of the offending field in the configuration, for instance `panels[0].title`.
+	Field string `json:"field,omitempty"`
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("invalid field %q: %s", e.Field, e.Err)
+	}
+
+	return e.Err
+}
+
+// Retryable tells if executing the generator again with the same configuration could succeed.
+func (e *RuntimeError) Retryable() bool {
+	switch e.Code {
+	case ErrorCodeInvalidConfig, ErrorCodeUnsupportedVersion:
+		return false
+	default:
+		return true
+	}
+}
+
+// InvalidConfigError reports that the configuration field at the given path is invalid.
+func InvalidConfigError(field string, err error) error {
+	return &RuntimeError{Err: err.Error(), Code: ErrorCodeInvalidConfig, Field: field}
+}
+
+// InternalError reports an unexpected failure of the generator.
+func InternalError(err error) error {
+	return &RuntimeError{Err: err.Error(), Code: ErrorCodeInternal}
+}
+
+// UnsupportedVersionError reports that the generator does not support the requested version.
+func UnsupportedVersionError(version string) error {
+	return &RuntimeError{Err: fmt.Sprintf("unsupported version %q", version), Code: ErrorCodeUnsupportedVersion}
+}
+
+func toRuntimeError(err error) *RuntimeError {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr
+	}
+
+	return &RuntimeError{Err: err.Error(), Code: ErrorCodeInternal}
+}
@@ -7,6 +7,8 @@ import (
 
 const (
 	InputPath = "/dawg/input"
+	// ContextPath holds the facts about the environment the generator runs for, see ReadContext.
+	ContextPath = "/dawg/context"
 )
 
 func WriteOutput(buf []byte) uint64 {
@@ -19,11 +21,7 @@ func WriteOutput(buf []byte) uint64 {
 	return (uint64(ptr) << uint64(32)) | uint64(size)
 }
 
-type RuntimeError struct {
-	Err string `json:"err"`
-}
-
 func Error(err error) uint64 {
-	b, _ := json.Marshal(&RuntimeError{Err: err.Error()})
+	b, _ := json.Marshal(toRuntimeError(err))
 	return WriteOutput(append([]byte{'e'}, b...))
 }
@@ -0,0 +1,55 @@
+package gdk
+
+import "encoding/json"
+
+// LibraryPanel is a library panel output by a generator, either along with a dashboard or for a LibraryPanel resource.
+type LibraryPanel struct {
+	// UID identifies the library panel, dashboards link to it with this UID.
+	UID string `json:"uid"`
+	// FolderUID is the UID of the folder holding the library panel, the General folder if empty.
+	FolderUID string `json:"folderUid,omitempty"`
+	Name      string `json:"name"`
+	// Model is the panel, as found in the panels of a dashboard.
+	Model json.RawMessage `json:"model"`
+}
+
+// Resource wraps the library panel in an envelope resource.
+func (p *LibraryPanel) Resource() (EnvelopeResource, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return EnvelopeResource{}, err
+	}
+
+	return EnvelopeResource{Kind: ResourceKindLibraryPanel, Payload: payload}, nil
+}
+
+// GridPos is the position and size of a panel in a dashboard.
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// LibraryPanelLink identifies the library panel a dashboard panel links to.
+type LibraryPanelLink struct {
+	UID  string `json:"uid"`
+	Name string `json:"name,omitempty"`
+}
+
+// LibraryPanelRef is a dashboard panel rendering a library panel.
+// The controller does not apply a dashboard until the library panels it links to exist in Grafana.
+type LibraryPanelRef struct {
+	ID           int              `json:"id"`
+	GridPos      GridPos          `json:"gridPos"`
+	LibraryPanel LibraryPanelLink `json:"libraryPanel"`
+}
+
+// NewLibraryPanelRef returns a dashboard panel with the given id and position, rendering the library panel with the given UID.
+func NewLibraryPanelRef(id int, uid string, pos GridPos) LibraryPanelRef {
+	return LibraryPanelRef{
+		ID:           id,
+		GridPos:      pos,
+		LibraryPanel: LibraryPanelLink{UID: uid},
+	}
+}
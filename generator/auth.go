@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	// dockerHubHost is the registry host docker associates with the legacy docker hub server URL.
+	dockerHubHost = "index.docker.io"
+	// dockerHubServerURL is the server URL docker uses to store docker hub credentials.
+	dockerHubServerURL = "https://index.docker.io/v1/"
+)
+
+// CredentialFunc resolves the credentials to use with the registry at the given host:port.
+// It returns an empty credential if no credentials are configured for this registry.
+type CredentialFunc func(ctx context.Context, hostport string) (auth.Credential, error)
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context carrying credentials to use when pulling or pushing a generator.
+// They take precedence over the credentials configured on the store.
+func WithCredentials(ctx context.Context, credentials CredentialFunc) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, credentials)
+}
+
+func credentialsFrom(ctx context.Context) (CredentialFunc, bool) {
+	credentials, ok := ctx.Value(credentialsKey{}).(CredentialFunc)
+	return credentials, ok
+}
+
+// ChainCredentials returns the first non empty credential resolved by the given functions.
+func ChainCredentials(fns ...CredentialFunc) CredentialFunc {
+	return func(ctx context.Context, hostport string) (auth.Credential, error) {
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+
+			cred, err := fn(ctx, hostport)
+			if err != nil {
+				return auth.EmptyCredential, err
+			}
+
+			if cred != auth.EmptyCredential {
+				return cred, nil
+			}
+		}
+
+		return auth.EmptyCredential, nil
+	}
+}
+
+// DockerConfig holds registry credentials, using the format of the docker config file
+// and of kubernetes.io/dockerconfigjson secrets.
+type DockerConfig struct {
+	Auths       map[string]DockerAuth `json:"auths"`
+	CredsStore  string                `json:"credsStore,omitempty"`
+	CredHelpers map[string]string     `json:"credHelpers,omitempty"`
+}
+
+// DockerAuth are the credentials of a single registry.
+type DockerAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// DefaultDockerConfigPath returns the location of the docker config file of the current user.
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads a docker config file, a missing file is an empty config.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	if path == "" {
+		return &DockerConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &DockerConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDockerConfig(data)
+}
+
+// ParseDockerConfig decodes a docker config, as found in a docker config file or a kubernetes.io/dockerconfigjson secret.
+func ParseDockerConfig(data []byte) (*DockerConfig, error) {
+	var cfg DockerConfig
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not decode docker config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// ParseLegacyDockerConfig decodes the legacy .dockercfg format used by kubernetes.io/dockercfg secrets.
+func ParseLegacyDockerConfig(data []byte) (*DockerConfig, error) {
+	var auths map[string]DockerAuth
+
+	if err := json.Unmarshal(data, &auths); err != nil {
+		return nil, fmt.Errorf("could not decode docker config: %w", err)
+	}
+
+	return &DockerConfig{Auths: auths}, nil
+}
+
+// Credential resolves the credentials for the given registry, from the credential helpers first and then from the inline auths.
+func (c *DockerConfig) Credential(ctx context.Context, hostport string) (auth.Credential, error) {
+	host := registryHost(hostport)
+
+	for server, helper := range c.CredHelpers {
+		if registryHost(server) == host {
+			return helperCredential(ctx, helper, helperServerURL(host))
+		}
+	}
+
+	for server, dockerAuth := range c.Auths {
+		if registryHost(server) == host {
+			return dockerAuth.credential()
+		}
+	}
+
+	if c.CredsStore != "" {
+		return helperCredential(ctx, c.CredsStore, helperServerURL(host))
+	}
+
+	return auth.EmptyCredential, nil
+}
+
+func (a DockerAuth) credential() (auth.Credential, error) {
+	cred := auth.Credential{
+		Username:     a.Username,
+		Password:     a.Password,
+		RefreshToken: a.IdentityToken,
+		AccessToken:  a.RegistryToken,
+	}
+
+	if a.Auth == "" {
+		return cred, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return auth.EmptyCredential, fmt.Errorf("could not decode registry auth: %w", err)
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return auth.EmptyCredential, errors.New("could not decode registry auth: missing password")
+	}
+
+	cred.Username = username
+	cred.Password = password
+
+	return cred, nil
+}
+
+// registryHost extracts the host:port of a registry from a server entry, which can be an URL.
+func registryHost(server string) string {
+	host := server
+
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+
+	host, _, _ = strings.Cut(host, "/")
+
+	// Docker keeps using this legacy server URL for docker hub credentials.
+	if host == dockerHubHost || host == "registry-1.docker.io" || host == "docker.io" {
+		return dockerHubHost
+	}
+
+	return host
+}
+
+func helperServerURL(host string) string {
+	if host == dockerHubHost {
+		return dockerHubServerURL
+	}
+
+	return host
+}
+
+// helperCredential runs a docker credential helper, see https://github.com/docker/docker-credential-helpers.
+func helperCredential(ctx context.Context, helper, serverURL string) (auth.Credential, error) {
+	if strings.ContainsAny(helper, `/\`) {
+		return auth.EmptyCredential, fmt.Errorf("invalid credential helper name %q", helper)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Helpers report missing credentials on stdout.
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return auth.EmptyCredential, nil
+		}
+
+		return auth.EmptyCredential, fmt.Errorf("credential helper %q failed: %w: %s", helper, err, stderr.String())
+	}
+
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return auth.EmptyCredential, fmt.Errorf("could not decode credential helper %q output: %w", helper, err)
+	}
+
+	// Helpers use this username to return an identity token instead of a password.
+	if resp.Username == "<token>" {
+		return auth.Credential{RefreshToken: resp.Secret}, nil
+	}
+
+	return auth.Credential{Username: resp.Username, Password: resp.Secret}, nil
+}
@@ -0,0 +1,93 @@
+package generator_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlevesy/dawg/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+func TestDockerConfig_Credential(t *testing.T) {
+	cfg, err := generator.ParseDockerConfig([]byte(`{
+		"auths": {
+			"registry.localhost:5000": {"auth": "Ym9iOnMzY3IzdA=="},
+			"https://index.docker.io/v1/": {"username": "alice", "password": "hunter2"},
+			"tokens.localhost": {"identitytoken": "refresh-token"}
+		}
+	}`))
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc     string
+		hostport string
+		want     auth.Credential
+	}{
+		{
+			desc:     "encoded auth",
+			hostport: "registry.localhost:5000",
+			want:     auth.Credential{Username: "bob", Password: "s3cr3t"},
+		},
+		{
+			desc:     "docker hub",
+			hostport: "registry-1.docker.io",
+			want:     auth.Credential{Username: "alice", Password: "hunter2"},
+		},
+		{
+			desc:     "identity token",
+			hostport: "tokens.localhost",
+			want:     auth.Credential{RefreshToken: "refresh-token"},
+		},
+		{
+			desc:     "unknown registry",
+			hostport: "registry.localhost:5001",
+			want:     auth.EmptyCredential,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			got, err := cfg.Credential(context.Background(), testCase.hostport)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.want, got)
+		})
+	}
+}
+
+func TestLoadDockerConfig(t *testing.T) {
+	workDir := t.TempDir()
+
+	cfg, err := generator.LoadDockerConfig(filepath.Join(workDir, "missing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, &generator.DockerConfig{}, cfg)
+
+	cfgPath := filepath.Join(workDir, "config.json")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`{"auths":{"registry.localhost":{"auth":"Ym9iOnMzY3IzdA=="}}}`), 0600))
+
+	cfg, err = generator.LoadDockerConfig(cfgPath)
+	require.NoError(t, err)
+
+	got, err := cfg.Credential(context.Background(), "registry.localhost")
+	require.NoError(t, err)
+	assert.Equal(t, auth.Credential{Username: "bob", Password: "s3cr3t"}, got)
+}
+
+func TestChainCredentials(t *testing.T) {
+	var (
+		empty = func(context.Context, string) (auth.Credential, error) {
+			return auth.EmptyCredential, nil
+		}
+		bob = func(context.Context, string) (auth.Credential, error) {
+			return auth.Credential{Username: "bob"}, nil
+		}
+		alice = func(context.Context, string) (auth.Credential, error) {
+			return auth.Credential{Username: "alice"}, nil
+		}
+	)
+
+	got, err := generator.ChainCredentials(nil, empty, bob, alice)(context.Background(), "registry.localhost")
+	require.NoError(t, err)
+	assert.Equal(t, auth.Credential{Username: "bob"}, got)
+}
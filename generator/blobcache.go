@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+const defaultBlobCacheMaxSize = 1 << 30
+
+// blobCache keeps the pulled generators on disk, following the OCI image layout.
+// When it grows over its maximum size, the least recently used blobs are deleted.
+// Tags are mutable, they are only kept in memory to let oras copy content in and out of the cache.
+type blobCache struct {
+	storage *oci.Storage
+	root    string
+	maxSize int64
+
+	// mu is held for reading while the cache is in use, and for writing while collecting garbage.
+	mu sync.RWMutex
+
+	tagsMu sync.Mutex
+	tags   map[string]ocispec.Descriptor
+
+	// grants are the generators the registries granted access to, per repository and credentials.
+	// They are only kept in memory, so that the registry is checked again after a restart.
+	grantsMu sync.Mutex
+	grants   map[string]struct{}
+
+	// resolutions are the digests the tags of each repository last resolved to, to serve tags when the registry is unreachable.
+	// Like grants, they are only kept in memory.
+	resolutionsMu sync.Mutex
+	resolutions   map[string]digest.Digest
+}
+
+func newBlobCache(root string, maxSize int64) (*blobCache, error) {
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+
+	storage, err := oci.NewStorage(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxSize <= 0 {
+		maxSize = defaultBlobCacheMaxSize
+	}
+
+	return &blobCache{
+		storage: storage,
+		root:    root,
+		maxSize: maxSize,
+		tags:    make(map[string]ocispec.Descriptor),
+		grants:  make(map[string]struct{}),
+
+		resolutions: make(map[string]digest.Digest),
+	}, nil
+}
+
+func (c *blobCache) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := c.storage.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// The modification time tracks the last use of a blob, so that the garbage collection keeps the hot ones.
+	now := time.Now()
+	_ = os.Chtimes(c.blobPath(target.Digest), now, now)
+
+	return rc, nil
+}
+
+func (c *blobCache) Push(ctx context.Context, expected ocispec.Descriptor, content io.Reader) error {
+	return c.storage.Push(ctx, expected, content)
+}
+
+func (c *blobCache) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	// oras skips the blobs referenced by an existing manifest, which might have been collected since.
+	// Manifests are always copied again, so that oras checks each of their blobs.
+	if target.MediaType == ocispec.MediaTypeImageManifest {
+		return false, nil
+	}
+
+	return c.storage.Exists(ctx, target)
+}
+
+func (c *blobCache) Tag(_ context.Context, desc ocispec.Descriptor, reference string) error {
+	c.tagsMu.Lock()
+	defer c.tagsMu.Unlock()
+
+	c.tags[reference] = desc
+
+	return nil
+}
+
+func (c *blobCache) Resolve(_ context.Context, reference string) (ocispec.Descriptor, error) {
+	c.tagsMu.Lock()
+	defer c.tagsMu.Unlock()
+
+	desc, ok := c.tags[reference]
+	if !ok {
+		return ocispec.Descriptor{}, errdef.ErrNotFound
+	}
+
+	return desc, nil
+}
+
+// grant records that a registry granted access to a generator, see registryStore.grantKey.
+func (c *blobCache) grant(key string) {
+	c.grantsMu.Lock()
+	defer c.grantsMu.Unlock()
+
+	c.grants[key] = struct{}{}
+}
+
+func (c *blobCache) granted(key string) bool {
+	c.grantsMu.Lock()
+	defer c.grantsMu.Unlock()
+
+	_, ok := c.grants[key]
+
+	return ok
+}
+
+// resolve records the digest a reference of a repository resolved to in the registry.
+func (c *blobCache) resolve(repository, reference string, dgst digest.Digest) {
+	if _, err := digest.Parse(reference); err == nil {
+		return
+	}
+
+	c.resolutionsMu.Lock()
+	defer c.resolutionsMu.Unlock()
+
+	c.resolutions[repository+":"+reference] = dgst
+}
+
+// resolved returns the digest of a reference of a repository, the last one it resolved to if it is a tag.
+func (c *blobCache) resolved(repository, reference string) (digest.Digest, bool) {
+	if dgst, err := digest.Parse(reference); err == nil {
+		return dgst, true
+	}
+
+	c.resolutionsMu.Lock()
+	defer c.resolutionsMu.Unlock()
+
+	dgst, ok := c.resolutions[repository+":"+reference]
+
+	return dgst, ok
+}
+
+// manifest returns the descriptor of a cached manifest, allowing to serve generators pinned by digest without pulling them again.
+func (c *blobCache) manifest(ctx context.Context, dgst digest.Digest) (ocispec.Descriptor, bool) {
+	info, err := os.Stat(c.blobPath(dgst))
+	if err != nil {
+		return ocispec.Descriptor{}, false
+	}
+
+	desc := ocispec.Descriptor{Digest: dgst, Size: info.Size()}
+
+	manifestBytes, err := content.FetchAll(ctx, c, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, false
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil || manifest.MediaType != ocispec.MediaTypeImageManifest {
+		return ocispec.Descriptor{}, false
+	}
+
+	for _, blob := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		if exists, err := c.storage.Exists(ctx, blob); err != nil || !exists {
+			return ocispec.Descriptor{}, false
+		}
+	}
+
+	desc.MediaType = manifest.MediaType
+
+	return desc, true
+}
+
+// use marks the cache as being used, garbage is not collected until the returned function is called.
+func (c *blobCache) use() func() {
+	c.mu.RLock()
+	return c.mu.RUnlock
+}
+
+type cachedBlob struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// collectGarbage deletes the least recently used blobs until the cache fits its maximum size.
+func (c *blobCache) collectGarbage() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		blobs []cachedBlob
+		size  int64
+	)
+
+	err := filepath.WalkDir(filepath.Join(c.root, ocispec.ImageBlobsDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		blobs = append(blobs, cachedBlob{path: path, size: info.Size(), modTime: info.ModTime()})
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if size <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].modTime.Before(blobs[j].modTime)
+	})
+
+	for _, blob := range blobs {
+		if size <= c.maxSize {
+			break
+		}
+
+		if err := os.Remove(blob.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		size -= blob.size
+	}
+
+	return nil
+}
+
+func (c *blobCache) blobPath(dgst digest.Digest) string {
+	return filepath.Join(c.root, ocispec.ImageBlobsDir, dgst.Algorithm().String(), dgst.Encoded())
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jlevesy/dawg/internal/lru"
+	"github.com/opencontainers/go-digest"
+	"github.com/tetratelabs/wazero"
+)
+
+// moduleCache keeps the most recently used compiled modules in memory, indexed by the digest of the generator binary.
+// Least recently used modules are evicted when the cache is full, and closed once the callers using them release them.
+type moduleCache struct {
+	mu      sync.Mutex
+	modules *lru.Cache[digest.Digest, *moduleCacheEntry]
+}
+
+type moduleCacheEntry struct {
+	module wazero.CompiledModule
+	// refs counts the callers using the module, it is only closed once evicted and unused.
+	refs    int
+	evicted bool
+}
+
+func newModuleCache(maxEntries int) *moduleCache {
+	var c moduleCache
+
+	c.modules = lru.New(maxEntries, func(_ digest.Digest, entry *moduleCacheEntry) { c.evict(entry) })
+
+	return &c
+}
+
+// Get returns the compiled module matching the given digest, if any.
+// The returned release func must be called once the caller is done with the module.
+func (c *moduleCache) Get(dgst digest.Digest) (wazero.CompiledModule, func(context.Context), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.modules.Get(dgst)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return entry.module, c.acquire(entry), true
+}
+
+// Add stores a compiled module and returns the module that should be used by the caller, along with its release func.
+// If another module has been cached for the same digest in the meantime, the given module is closed and the cached one is returned.
+func (c *moduleCache) Add(ctx context.Context, dgst digest.Digest, mod wazero.CompiledModule) (wazero.CompiledModule, func(context.Context)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.modules.Get(dgst); ok {
+		_ = mod.Close(ctx)
+
+		return entry.module, c.acquire(entry)
+	}
+
+	entry := &moduleCacheEntry{module: mod}
+	release := c.acquire(entry)
+
+	c.modules.Add(dgst, entry)
+
+	return mod, release
+}
+
+// acquire marks the module of an entry as used, until the returned func is called. c.mu must be held.
+func (c *moduleCache) acquire(entry *moduleCacheEntry) func(context.Context) {
+	entry.refs++
+
+	var once sync.Once
+
+	return func(ctx context.Context) {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			entry.refs--
+
+			if entry.evicted && entry.refs == 0 {
+				_ = entry.module.Close(ctx)
+			}
+		})
+	}
+}
+
+// evict marks an entry evicted from the cache, its module is closed now if unused, otherwise on its last release. c.mu must be held.
+func (c *moduleCache) evict(entry *moduleCacheEntry) {
+	entry.evicted = true
+
+	// Modules already instantiated from this compiled module keep working after the close.
+	// Closing a compiled module only releases its resources, it does not depend on the context of a caller.
+	if entry.refs == 0 {
+		_ = entry.module.Close(context.Background())
+	}
+}
+
+// Close evicts all the compiled modules held by the cache.
+func (c *moduleCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.modules.Purge()
+
+	return nil
+}
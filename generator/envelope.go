@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+var envelopeMarker = []byte(`"envelope"`)
+
+// decodeEnvelope extracts the resources carried by an output envelope.
+// It reports false if the given payload is not an envelope.
+func decodeEnvelope(payload []byte) ([]Resource, bool, error) {
+	// Avoid decoding every payload, most of them are plain dashboards.
+	if !bytes.Contains(payload, envelopeMarker) {
+		return nil, false, nil
+	}
+
+	var probe struct {
+		Version string `json:"envelope"`
+	}
+
+	if err := json.Unmarshal(payload, &probe); err != nil || probe.Version == "" {
+		return nil, false, nil
+	}
+
+	if probe.Version != gdk.EnvelopeVersion {
+		return nil, false, unsupportedEnvelopeError(probe.Version)
+	}
+
+	var envelope gdk.Envelope
+
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, false, fmt.Errorf("could not decode output envelope: %w", err)
+	}
+
+	resources := make([]Resource, 0, len(envelope.Resources))
+
+	for i, res := range envelope.Resources {
+		if res.Kind == "" {
+			return nil, false, fmt.Errorf("resource %d of the output envelope has no kind", i)
+		}
+
+		resources = append(resources, Resource{Kind: res.Kind, Payload: res.Payload})
+	}
+
+	return resources, true, nil
+}
+
+type unsupportedEnvelopeError string
+
+func (u unsupportedEnvelopeError) Error() string {
+	return fmt.Sprintf("unsupported output envelope version %q", string(u))
+}
@@ -2,22 +2,63 @@ package generator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/url"
 	"os"
 	"path/filepath"
 )
 
-const fileScheme = "file"
+const (
+	fileScheme = "file"
 
-type fileStore struct{}
+	// manifestFileSuffix is appended to the generator path to store its manifest next to it.
+	manifestFileSuffix = ".manifest.json"
+)
+
+type fileStore struct {
+	// requireSignature refuses to load generators, as files can't be signed.
+	requireSignature bool
+}
+
+func (f *fileStore) Load(ctx context.Context, url *url.URL) (*Generator, error) {
+	if f.requireSignature {
+		return nil, fmt.Errorf("%w: generators loaded from files are not signed", ErrUnverifiedGenerator)
+	}
 
-func (f *fileStore) Load(_ context.Context, url *url.URL) (*Generator, error) {
 	bin, err := os.ReadFile(url.Path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Generator{Bin: bin}, nil
+	manifest, err := f.Inspect(ctx, url)
+	switch {
+	case errors.Is(err, ErrNoManifest):
+		return &Generator{Bin: bin}, nil
+	case err != nil:
+		return nil, err
+	default:
+		return &Generator{Bin: bin, Manifest: manifest}, nil
+	}
+}
+
+func (f *fileStore) Inspect(_ context.Context, url *url.URL) (*Manifest, error) {
+	manifestBytes, err := os.ReadFile(url.Path + manifestFileSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
 }
 
 func (f *fileStore) Store(_ context.Context, url *url.URL, g *Generator) error {
@@ -25,5 +66,20 @@ func (f *fileStore) Store(_ context.Context, url *url.URL, g *Generator) error {
 		return err
 	}
 
-	return os.WriteFile(filepath.Join(url.Host, url.Path), g.Bin, 0600)
+	genPath := filepath.Join(url.Host, url.Path)
+
+	if err := os.WriteFile(genPath, g.Bin, 0600); err != nil {
+		return err
+	}
+
+	if g.Manifest == nil {
+		return nil
+	}
+
+	manifestBytes, err := json.Marshal(g.Manifest)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(genPath+manifestFileSuffix, manifestBytes, 0600)
 }
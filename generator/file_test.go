@@ -34,3 +34,44 @@ func TestStore_Filesystem(t *testing.T) {
 
 	assert.Equal(t, &gen, gotGen)
 }
+
+func TestStore_FilesystemManifest(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		workDir = t.TempDir()
+		gen     = generator.Generator{
+			Bin: []byte("coucou"),
+			Manifest: &generator.Manifest{
+				Name:         "test",
+				Description:  "A test generator",
+				Version:      "v0.0.1",
+				ABIVersion:   "dawg_v1",
+				ConfigSchema: []byte(`{"type":"object"}`),
+				Authors:      []string{"Bob"},
+				SourceURL:    "https://github.com/jlevesy/dawg",
+			},
+		}
+	)
+
+	genStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	genUrl, err := url.Parse("file://" + filepath.Join(workDir, "test.wasm"))
+	require.NoError(t, err)
+
+	_, err = generator.Inspect(ctx, genStore, genUrl)
+	require.ErrorIs(t, err, generator.ErrNoManifest)
+
+	err = genStore.Store(ctx, genUrl, &gen)
+	require.NoError(t, err)
+
+	gotGen, err := genStore.Load(ctx, genUrl)
+	require.NoError(t, err)
+
+	assert.Equal(t, &gen, gotGen)
+
+	gotManifest, err := generator.Inspect(ctx, genStore, genUrl)
+	require.NoError(t, err)
+
+	assert.Equal(t, gen.Manifest, gotManifest)
+}
@@ -1,6 +1,18 @@
 package generator
 
+import "github.com/opencontainers/go-digest"
+
 // Generator is a WASM executable binary.
 type Generator struct {
 	Bin []byte
+	// Manifest optionally describes the generator.
+	Manifest *Manifest
+	// ResolvedDigest is the digest of the OCI manifest the generator has been pulled from, if it comes from a registry.
+	// Referencing the generator with it guarantees to get the same generator again.
+	ResolvedDigest digest.Digest
+}
+
+// Digest returns the content digest of the generator binary.
+func (g *Generator) Digest() digest.Digest {
+	return digest.FromBytes(g.Bin)
 }
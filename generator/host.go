@@ -0,0 +1,171 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Resource is an additional resource emitted by a generator alongside its main output.
+type Resource struct {
+	Kind    string
+	Payload []byte
+}
+
+// LogEntry is a message logged by a generator through the host ABI.
+type LogEntry struct {
+	Level   gdk.LogLevel
+	Message string
+}
+
+// executionState collects what a generator reports during a single execution.
+type executionState struct {
+	maxOutputSize uint32
+	maxLogSize    int
+
+	stdout     boundedBuffer
+	stderr     boundedBuffer
+	output     []byte
+	errPayload []byte
+	resources  []Resource
+	// outputSize is the size of the output and resources reported so far, checked against maxOutputSize.
+	outputSize uint64
+	logs       []LogEntry
+	logSize    int
+	// hostErr records a failure in a host call, host functions can't fail the guest call themselves.
+	hostErr error
+}
+
+type executionStateKey struct{}
+
+func withExecutionState(ctx context.Context, state *executionState) context.Context {
+	return context.WithValue(ctx, executionStateKey{}, state)
+}
+
+func executionStateFrom(ctx context.Context) (*executionState, bool) {
+	state, ok := ctx.Value(executionStateKey{}).(*executionState)
+	return state, ok
+}
+
+func (s *executionState) executionError(err error) *ExecutionError {
+	return &ExecutionError{
+		Stdout: s.stdout.Bytes(),
+		Stderr: s.stderr.Bytes(),
+		Logs:   s.logs,
+		Err:    err,
+	}
+}
+
+// reported tells if the generator reported its result through the host ABI.
+func (s *executionState) reported() bool {
+	return s.output != nil || s.errPayload != nil || len(s.resources) > 0
+}
+
+func instantiateHostModule(ctx context.Context, r wazero.Runtime) error {
+	_, err := r.NewHostModuleBuilder(gdk.HostModule).
+		NewFunctionBuilder().WithFunc(hostLog).Export("log").
+		NewFunctionBuilder().WithFunc(hostSetOutput).Export("set_output").
+		NewFunctionBuilder().WithFunc(hostSetError).Export("set_error").
+		NewFunctionBuilder().WithFunc(hostAddResource).Export("add_resource").
+		Instantiate(ctx)
+
+	return err
+}
+
+func hostLog(ctx context.Context, mod api.Module, level, ptr, size uint32) {
+	state, ok := executionStateFrom(ctx)
+	if !ok || state.logSize+int(size) > state.maxLogSize {
+		return
+	}
+
+	msg, ok := readGuestBytes(mod, ptr, size)
+	if !ok {
+		return
+	}
+
+	state.logSize += len(msg)
+	state.logs = append(state.logs, LogEntry{Level: gdk.LogLevel(level), Message: string(msg)})
+}
+
+func hostSetOutput(ctx context.Context, mod api.Module, ptr, size uint32) {
+	state, ok := executionStateFrom(ctx)
+	if !ok || !state.checkOutputSize(uint32(len(state.output)), size) {
+		return
+	}
+
+	output, ok := readGuestBytes(mod, ptr, size)
+	if !ok {
+		state.hostErr = errFailedToReadMemory
+		return
+	}
+
+	state.output = output
+}
+
+func hostSetError(ctx context.Context, mod api.Module, ptr, size uint32) {
+	state, ok := executionStateFrom(ctx)
+	if !ok {
+		return
+	}
+
+	errPayload, ok := readGuestBytes(mod, ptr, size)
+	if !ok {
+		state.hostErr = errFailedToReadMemory
+		return
+	}
+
+	state.errPayload = errPayload
+}
+
+func hostAddResource(ctx context.Context, mod api.Module, kindPtr, kindSize, ptr, size uint32) {
+	state, ok := executionStateFrom(ctx)
+	if !ok || !state.checkOutputSize(0, size) {
+		return
+	}
+
+	kind, ok := readGuestBytes(mod, kindPtr, kindSize)
+	if !ok {
+		state.hostErr = errFailedToReadMemory
+		return
+	}
+
+	payload, ok := readGuestBytes(mod, ptr, size)
+	if !ok {
+		state.hostErr = errFailedToReadMemory
+		return
+	}
+
+	state.resources = append(state.resources, Resource{Kind: string(kind), Payload: payload})
+}
+
+// checkOutputSize adds size bytes to the output reported so far, in place of replaced bytes,
+// and fails the execution if the total goes over the limit.
+func (s *executionState) checkOutputSize(replaced, size uint32) bool {
+	total := s.outputSize - uint64(replaced) + uint64(size)
+
+	if s.maxOutputSize > 0 && total > uint64(s.maxOutputSize) {
+		s.hostErr = &OutputSizeError{Size: total, MaxSize: s.maxOutputSize}
+		return false
+	}
+
+	s.outputSize = total
+
+	return true
+}
+
+// readGuestBytes copies a buffer out of the guest memory, as the guest is free to reuse it after the host call.
+func readGuestBytes(mod api.Module, ptr, size uint32) ([]byte, bool) {
+	if size == 0 {
+		return []byte{}, true
+	}
+
+	buf, ok := mod.Memory().Read(ptr, size)
+	if !ok {
+		return nil, false
+	}
+
+	return bytes.Clone(buf), true
+}
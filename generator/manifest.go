@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const mediaTypeGeneratorConfig = "application/vnd.dawg.generator.config.v1+json"
+
+// ErrNoManifest is returned when a generator has been stored without a manifest.
+var ErrNoManifest = errors.New("generator has no manifest")
+
+// Manifest describes a generator.
+type Manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	// ABIVersion is the host ABI version the generator has been built against, dawg_v1 for instance.
+	ABIVersion string `json:"abiVersion,omitempty"`
+	// ConfigSchema is the JSON Schema of the configuration accepted by the generator.
+	ConfigSchema json.RawMessage `json:"configSchema,omitempty"`
+	Authors      []string        `json:"authors,omitempty"`
+	SourceURL    string          `json:"sourceURL,omitempty"`
+}
+
+// Inspector allows to retrieve the manifest of a generator without loading its binary.
+type Inspector interface {
+	Inspect(context.Context, *url.URL) (*Manifest, error)
+}
+
+// Inspect returns the manifest of the generator located at the given URL.
+// It falls back to loading the generator if the reader does not know how to inspect it.
+func Inspect(ctx context.Context, r Reader, url *url.URL) (*Manifest, error) {
+	if inspector, ok := r.(Inspector); ok {
+		return inspector.Inspect(ctx, url)
+	}
+
+	gen, err := r.Load(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if gen.Manifest == nil {
+		return nil, ErrNoManifest
+	}
+
+	return gen.Manifest, nil
+}
+
+// annotations exposes the manifest as standard OCI annotations, so that registry tooling can display it.
+func (m *Manifest) annotations() map[string]string {
+	annotations := make(map[string]string)
+
+	setAnnotation(annotations, ocispec.AnnotationTitle, m.Name)
+	setAnnotation(annotations, ocispec.AnnotationDescription, m.Description)
+	setAnnotation(annotations, ocispec.AnnotationVersion, m.Version)
+	setAnnotation(annotations, ocispec.AnnotationAuthors, strings.Join(m.Authors, ", "))
+	setAnnotation(annotations, ocispec.AnnotationSource, m.SourceURL)
+
+	return annotations
+}
+
+func setAnnotation(annotations map[string]string, key, value string) {
+	if value != "" {
+		annotations[key] = value
+	}
+}
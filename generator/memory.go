@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// errMemoryLimit aborts a generator call trying to grow its memory over the limit.
+var errMemoryLimit = errors.New("memory.grow over the memory limit")
+
+// memoryLimiter allocates the linear memory of a generator module, and refuses to grow it over a limit.
+// wazero fails the grows going over the maximum of a memory without telling the host,
+// enforcing the limit here tells the runtime when a generator actually ran out of memory.
+type memoryLimiter struct {
+	maxBytes uint64
+	exceeded atomic.Bool
+}
+
+func newMemoryLimiter(maxPages uint32) *memoryLimiter {
+	return &memoryLimiter{maxBytes: uint64(maxPages) * wasmPageSize}
+}
+
+// Allocate implements experimental.MemoryAllocator.
+func (l *memoryLimiter) Allocate(capacity, _ uint64) experimental.LinearMemory {
+	return &limitedMemory{limiter: l, buf: make([]byte, 0, capacity)}
+}
+
+// Exceeded tells if the module tried to use more memory than allowed, false for a nil limiter.
+func (l *memoryLimiter) Exceeded() bool {
+	return l != nil && l.exceeded.Load()
+}
+
+type limitedMemory struct {
+	limiter   *memoryLimiter
+	buf       []byte
+	allocated bool
+}
+
+// Reallocate implements experimental.LinearMemory.
+func (m *limitedMemory) Reallocate(size uint64) []byte {
+	if size > m.limiter.maxBytes {
+		m.limiter.exceeded.Store(true)
+
+		// Grows happen during calls, where wazero turns the panic into an error.
+		// The initial allocation can't be refused, the runtime rejects the module once instantiated.
+		if m.allocated {
+			panic(errMemoryLimit)
+		}
+	}
+
+	m.allocated = true
+
+	if n := uint64(len(m.buf)); size > n {
+		m.buf = append(m.buf, make([]byte, size-n)...)
+	} else {
+		m.buf = m.buf[:size]
+	}
+
+	return m.buf
+}
+
+// Free implements experimental.LinearMemory.
+func (m *limitedMemory) Free() {
+	m.buf = nil
+}
+
+// checkDeclaredMemory rejects the modules declaring more initial memory than allowed, before allocating it.
+func checkDeclaredMemory(compiled wazero.CompiledModule, maxPages uint32) error {
+	for _, def := range compiled.ExportedMemories() {
+		if def.Min() > maxPages {
+			return fmt.Errorf("module declares a memory of at least %d pages", def.Min())
+		}
+	}
+
+	return nil
+}
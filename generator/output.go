@@ -0,0 +1,28 @@
+package generator
+
+import "bytes"
+
+// boundedBuffer keeps at most max bytes written to it and silently discards the rest.
+type boundedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	remaining := b.max - b.buf.Len()
+
+	switch {
+	case remaining <= 0:
+	case len(p) > remaining:
+		_, _ = b.buf.Write(p[:remaining])
+	default:
+		_, _ = b.buf.Write(p)
+	}
+
+	// Always report the full write, a chatty generator should not fail because of its logs.
+	return len(p), nil
+}
+
+func (b *boundedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
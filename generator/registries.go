@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"oras.land/oras-go/v2/registry/remote/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// RegistriesConfig configures how generator registries are reached.
+type RegistriesConfig struct {
+	Registries []RegistryConfig `json:"registries"`
+}
+
+// RegistryConfig configures a single registry.
+type RegistryConfig struct {
+	// Host is the host[:port] of the registry, as found in generator URLs.
+	Host string `json:"host"`
+	// Location rewrites the registry to another host[:port][/path], the generator repository is appended to it.
+	Location string `json:"location,omitempty"`
+	// Mirrors are tried in order before the registry when pulling a generator, their settings are the ones of their host.
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	PlainHTTP          bool `json:"plainHTTP,omitempty"`
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CAFile is a PEM bundle of the certificate authorities trusted for this registry, on top of the system ones.
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the PEM client certificate and key presented to the registry.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+}
+
+// DefaultRegistriesConfig is used when no registries config is given, it allows plain HTTP to local registries.
+func DefaultRegistriesConfig() *RegistriesConfig {
+	return &RegistriesConfig{
+		Registries: []RegistryConfig{
+			{Host: "dawg-dev.localhost", PlainHTTP: true},
+			{Host: "localhost", PlainHTTP: true},
+		},
+	}
+}
+
+// LoadRegistriesConfig reads a YAML or JSON registries config file.
+func LoadRegistriesConfig(path string) (*RegistriesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg RegistriesConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not decode registries config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// registrySettings is the resolved configuration of a registry.
+type registrySettings struct {
+	location  string
+	mirrors   []string
+	plainHTTP bool
+	client    *http.Client
+}
+
+// hostSettings resolves the settings of every configured registry, indexed by host.
+func hostSettings(cfg *RegistriesConfig) (map[string]*registrySettings, error) {
+	settings := make(map[string]*registrySettings, len(cfg.Registries))
+
+	for _, reg := range cfg.Registries {
+		if reg.Host == "" {
+			return nil, errors.New("registry config is missing a host")
+		}
+
+		if _, ok := settings[reg.Host]; ok {
+			return nil, fmt.Errorf("registry %q is configured more than once", reg.Host)
+		}
+
+		client, err := registryHTTPClient(reg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config for registry %q: %w", reg.Host, err)
+		}
+
+		settings[reg.Host] = &registrySettings{
+			location:  reg.Location,
+			mirrors:   reg.Mirrors,
+			plainHTTP: reg.PlainHTTP,
+			client:    client,
+		}
+	}
+
+	return settings, nil
+}
+
+func registryHTTPClient(reg RegistryConfig) (*http.Client, error) {
+	if !reg.InsecureSkipVerify && reg.CAFile == "" && reg.CertFile == "" && reg.KeyFile == "" {
+		return retry.DefaultClient, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Explicitly asked for by the operator, for registries using self signed certificates.
+		InsecureSkipVerify: reg.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if reg.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		caBundle, err := os.ReadFile(reg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in CA file %q", reg.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if reg.CertFile != "" || reg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(reg.CertFile, reg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: retry.NewTransport(transport)}, nil
+}
+
+// repositoryLocation returns where the repository of a registry is actually located, after applying the rewrite rules.
+func (s *registrySettings) repositoryLocation(host, repository string) string {
+	if s != nil && s.location != "" {
+		host = s.location
+	}
+
+	return path.Join(host, repository)
+}
@@ -0,0 +1,92 @@
+package generator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlevesy/dawg/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRegistriesConfig(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "registries.yaml")
+
+	require.NoError(
+		t,
+		os.WriteFile(
+			cfgPath,
+			[]byte(`
+registries:
+- host: registry.localhost:5000
+  plainHTTP: true
+- host: docker.io
+  location: registry.internal/dockerhub
+  mirrors:
+  - mirror.internal/dockerhub
+  insecureSkipVerify: true
+`),
+			0600,
+		),
+	)
+
+	cfg, err := generator.LoadRegistriesConfig(cfgPath)
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		&generator.RegistriesConfig{
+			Registries: []generator.RegistryConfig{
+				{Host: "registry.localhost:5000", PlainHTTP: true},
+				{
+					Host:               "docker.io",
+					Location:           "registry.internal/dockerhub",
+					Mirrors:            []string{"mirror.internal/dockerhub"},
+					InsecureSkipVerify: true,
+				},
+			},
+		},
+		cfg,
+	)
+
+	_, err = generator.DefaultStore(generator.WithRegistriesConfig(cfg))
+	require.NoError(t, err)
+}
+
+func TestDefaultStore_InvalidRegistriesConfig(t *testing.T) {
+	for _, testCase := range []struct {
+		desc string
+		cfg  generator.RegistriesConfig
+	}{
+		{
+			desc: "missing host",
+			cfg: generator.RegistriesConfig{
+				Registries: []generator.RegistryConfig{{PlainHTTP: true}},
+			},
+		},
+		{
+			desc: "duplicated host",
+			cfg: generator.RegistriesConfig{
+				Registries: []generator.RegistryConfig{{Host: "localhost"}, {Host: "localhost"}},
+			},
+		},
+		{
+			desc: "missing CA file",
+			cfg: generator.RegistriesConfig{
+				Registries: []generator.RegistryConfig{{Host: "localhost", CAFile: "/does/not/exist.pem"}},
+			},
+		},
+		{
+			desc: "missing client key",
+			cfg: generator.RegistriesConfig{
+				Registries: []generator.RegistryConfig{{Host: "localhost", CertFile: "/does/not/exist.pem"}},
+			},
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := generator.DefaultStore(generator.WithRegistriesConfig(&testCase.cfg))
+			require.Error(t, err)
+		})
+	}
+}
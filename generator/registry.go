@@ -3,9 +3,13 @@ package generator
 import (
 	"bytes"
 	"context"
+	"crypto"
+	// Registers sha256 for digest verification, https://github.com/opencontainers/go-digest#usage
+	_ "crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"net"
 	"net/url"
 	"path"
 
@@ -15,7 +19,11 @@ import (
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
 	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
 )
 
 const (
@@ -25,73 +33,90 @@ const (
 	atrifactTypeGenerator = "application/vnd.dawg.generator.v1"
 )
 
-var (
-	defaultRegistrySettings = registrySettings{
-		PlainHTTP: false,
-	}
-	defaultRegistriesSettings = map[string]registrySettings{
-		"dawg-dev.localhost": {
-			PlainHTTP: true,
-		},
-		"localhost": {
-			PlainHTTP: true,
-		},
-	}
-)
-
-type registrySettings struct {
-	PlainHTTP bool
-	// TODO handle registry auth.
-}
-
 type registryStore struct {
-	localStore         oras.Target
-	registriesSettings map[string]registrySettings
+	// cache is the on disk cache generators are pulled to, if enabled.
+	cache       *blobCache
+	registries  map[string]*registrySettings
+	credentials CredentialFunc
+	// signingKey signs the pushed generators, if set.
+	signingKey crypto.Signer
+	// verificationKeys are the keys one of the signatures of a generator must match, if set.
+	verificationKeys []crypto.PublicKey
 }
 
-func newRegistryStore() *registryStore {
-	// TODO(jly): configure store.
-	// TODO(jly): use filesystem local store!?
+// newRegistryStore returns a registry store, pulling generators to the given cache if any.
+func newRegistryStore(cfg *RegistriesConfig, cache *blobCache) (*registryStore, error) {
+	registries, err := hostSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &registryStore{
-		localStore:         memory.New(),
-		registriesSettings: defaultRegistriesSettings,
+		cache:      cache,
+		registries: registries,
+	}, nil
+}
+
+// localStore returns the store generators are copied to and from registries.
+// Without a cache, generators are not kept once loaded: each pull or push gets its own in memory store.
+func (st *registryStore) localStore() oras.Target {
+	if st.cache == nil {
+		return memory.New()
 	}
+
+	return st.cache
 }
 
 func (st *registryStore) Store(ctx context.Context, url *url.URL, gen *Generator) error {
+	defer st.useCache()()
+
+	localStore := st.localStore()
 	blobDescriptor := newDescriptorFromGenerator(gen)
 
-	if err := st.localStore.Push(ctx, blobDescriptor, bytes.NewReader(gen.Bin)); err != nil {
+	if err := localStore.Push(ctx, blobDescriptor, bytes.NewReader(gen.Bin)); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
 		return err
 	}
 
+	packOpts := oras.PackManifestOptions{
+		Layers: []v1.Descriptor{blobDescriptor},
+	}
+
+	if gen.Manifest != nil {
+		configDescriptor, err := st.pushManifestConfig(ctx, localStore, gen.Manifest)
+		if err != nil {
+			return err
+		}
+
+		packOpts.ConfigDescriptor = &configDescriptor
+		packOpts.ManifestAnnotations = gen.Manifest.annotations()
+	}
+
 	manifestDescriptor, err := oras.PackManifest(
 		ctx,
-		st.localStore,
+		localStore,
 		oras.PackManifestVersion1_1_RC4,
 		atrifactTypeGenerator,
-		oras.PackManifestOptions{
-			Layers: []v1.Descriptor{blobDescriptor},
-		},
+		packOpts,
 	)
 	if err != nil {
 		return err
 	}
 
-	repo, err := st.repoWithSettings(url)
+	// Mirrors are only used to pull generators.
+	repo, err := st.repository(ctx, st.settings(url.Host).repositoryLocation(url.Host, url.Path))
 	if err != nil {
 		return err
 	}
 
 	// TODO(jly): handle empty ref!!!!!
 
-	if err := st.localStore.Tag(ctx, manifestDescriptor, repo.Reference.Reference); err != nil {
+	if err := localStore.Tag(ctx, manifestDescriptor, repo.Reference.Reference); err != nil {
 		return err
 	}
 
 	if _, err := oras.Copy(
 		ctx,
-		st.localStore,
+		localStore,
 		repo.Reference.Reference,
 		repo,
 		repo.Reference.Reference,
@@ -100,81 +125,390 @@ func (st *registryStore) Store(ctx context.Context, url *url.URL, gen *Generator
 		return err
 	}
 
+	if st.signingKey != nil {
+		if err := pushSignature(ctx, repo, st.signingKey, manifestDescriptor); err != nil {
+			return fmt.Errorf("could not push generator signature: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Sign signs a generator already pushed to a registry.
+func (st *registryStore) Sign(ctx context.Context, url *url.URL, key crypto.Signer) error {
+	// Mirrors are only used to pull generators.
+	repo, err := st.repository(ctx, st.settings(url.Host).repositoryLocation(url.Host, url.Path))
+	if err != nil {
+		return err
+	}
+
+	subject, err := repo.Resolve(ctx, repo.Reference.ReferenceOrDefault())
+	if err != nil {
+		return fmt.Errorf("could not resolve generator: %w", err)
+	}
+
+	if err := pushSignature(ctx, repo, key, subject); err != nil {
+		return fmt.Errorf("could not push generator signature: %w", err)
+	}
+
 	return nil
 }
 
 func (st *registryStore) Load(ctx context.Context, url *url.URL) (*Generator, error) {
-	repo, err := st.repoWithSettings(url)
+	defer st.useCache()()
+
+	repos, err := st.pullRepositories(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	manifestDescriptor, err := oras.Copy(
+	var errs []error
+
+	for _, repo := range repos {
+		gen, err := st.load(ctx, repo)
+		if err == nil {
+			return gen, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", repo.Reference, err))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (st *registryStore) load(ctx context.Context, repo *remote.Repository) (*Generator, error) {
+	reference := repo.Reference.ReferenceOrDefault()
+
+	if len(st.verificationKeys) > 0 {
+		verified, err := verifySignatures(ctx, repo, reference, st.verificationKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pull what has been verified, the tag might have moved since.
+		reference = verified.Digest.String()
+	}
+
+	localStore := st.localStore()
+
+	manifestDescriptor, err := st.pull(ctx, localStore, repo, reference)
+	if err != nil {
+		return nil, fmt.Errorf("could not pull generator from registry: %w", err)
+	}
+
+	successors, err := content.Successors(ctx, localStore, manifestDescriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, ok := findSuccessor(successors, mediaTypeWasmLayer)
+	if !ok {
+		return nil, errors.New("no wasm layer")
+	}
+
+	buf, err := content.FetchAll(ctx, localStore, layer)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := Generator{Bin: buf, ResolvedDigest: manifestDescriptor.Digest}
+
+	if config, ok := findSuccessor(successors, mediaTypeGeneratorConfig); ok {
+		gen.Manifest, err = fetchManifestConfig(ctx, localStore, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &gen, nil
+}
+
+// pull copies a generator to the local store. Blobs already present in the cache are not pulled again.
+func (st *registryStore) pull(ctx context.Context, localStore oras.Target, repo *remote.Repository, reference string) (ocispec.Descriptor, error) {
+	if st.cache == nil {
+		return st.copy(ctx, localStore, repo, reference)
+	}
+
+	// The cache is shared by all the repositories and credentials, the registry must grant access to the generator
+	// before it is served from the cache. If the registry cannot be reached, the generator is still served from the cache
+	// if the registry granted access to it with the same credentials before, tags resolving to their last known digest.
+	desc, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		if !isNetworkError(err) {
+			return ocispec.Descriptor{}, err
+		}
+
+		dgst, ok := st.cache.resolved(repositoryName(repo), reference)
+		if !ok {
+			return ocispec.Descriptor{}, err
+		}
+
+		grant, grantErr := st.grantKey(ctx, repo, dgst)
+		if grantErr != nil {
+			return ocispec.Descriptor{}, grantErr
+		}
+
+		if !st.cache.granted(grant) {
+			return ocispec.Descriptor{}, err
+		}
+
+		cached, ok := st.cache.manifest(ctx, dgst)
+		if !ok {
+			return ocispec.Descriptor{}, err
+		}
+
+		return cached, nil
+	}
+
+	grant, err := st.grantKey(ctx, repo, desc.Digest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	st.cache.grant(grant)
+	st.cache.resolve(repositoryName(repo), reference, desc.Digest)
+
+	if cached, ok := st.cache.manifest(ctx, desc.Digest); ok {
+		return cached, nil
+	}
+
+	// Pull what has been resolved, the tag might have moved since.
+	return st.copy(ctx, localStore, repo, desc.Digest.String())
+}
+
+func (st *registryStore) copy(ctx context.Context, localStore oras.Target, repo *remote.Repository, reference string) (ocispec.Descriptor, error) {
+	return oras.Copy(
 		ctx,
 		repo,
-		repo.Reference.ReferenceOrDefault(),
-		st.localStore,
-		repo.Reference.ReferenceOrDefault(),
+		reference,
+		localStore,
+		reference,
 		oras.DefaultCopyOptions,
 	)
+}
+
+// grantKey identifies the access to a generator of a repository with the credentials of the context.
+func (st *registryStore) grantKey(ctx context.Context, repo *remote.Repository, dgst digest.Digest) (string, error) {
+	cred, err := st.credentialsFor(ctx)(ctx, repo.Reference.Host())
 	if err != nil {
-		return nil, fmt.Errorf("could not pull generator from registry: %w", err)
+		return "", err
+	}
+
+	key, err := json.Marshal(struct {
+		Repository string          `json:"repository"`
+		Credential auth.Credential `json:"credential"`
+		Digest     digest.Digest   `json:"digest"`
+	}{
+		Repository: repositoryName(repo),
+		Credential: cred,
+		Digest:     dgst,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Credentials are not kept in memory as is.
+	return digest.FromBytes(key).String(), nil
+}
+
+func repositoryName(repo *remote.Repository) string {
+	return repo.Reference.Registry + "/" + repo.Reference.Repository
+}
+
+// isNetworkError tells if the registry could not be reached, as opposed to the registry answering with an error.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// useCache prevents the cache garbage collection while a generator is pulled or pushed.
+// The returned function releases the cache and collects garbage.
+func (st *registryStore) useCache() func() {
+	if st.cache == nil {
+		return func() {}
+	}
+
+	release := st.cache.use()
+
+	return func() {
+		release()
+		// Best effort, failing to collect garbage must not fail the pull.
+		_ = st.cache.collectGarbage()
 	}
+}
 
-	successors, err := content.Successors(ctx, repo, manifestDescriptor)
+// Inspect reads the generator manifest from the registry, without pulling the generator binary.
+func (st *registryStore) Inspect(ctx context.Context, url *url.URL) (*Manifest, error) {
+	repos, err := st.pullRepositories(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	layer, ok := findWasmSuccessor(successors)
-	if !ok {
-		return nil, errors.New("no wasm layer")
+	var errs []error
+
+	for _, repo := range repos {
+		manifest, err := st.inspect(ctx, repo)
+		if err == nil || errors.Is(err, ErrNoManifest) {
+			return manifest, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", repo.Reference, err))
 	}
 
-	content, err := st.localStore.Fetch(ctx, layer)
+	return nil, errors.Join(errs...)
+}
+
+func (st *registryStore) inspect(ctx context.Context, repo *remote.Repository) (*Manifest, error) {
+	_, manifestBytes, err := oras.FetchBytes(
+		ctx,
+		repo,
+		repo.Reference.ReferenceOrDefault(),
+		oras.DefaultFetchBytesOptions,
+	)
 	if err != nil {
+		return nil, fmt.Errorf("could not fetch generator manifest from registry: %w", err)
+	}
+
+	var ociManifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &ociManifest); err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		_ = content.Close()
-	}()
+	if ociManifest.Config.MediaType != mediaTypeGeneratorConfig {
+		return nil, ErrNoManifest
+	}
 
-	buf, err := io.ReadAll(content)
+	return fetchManifestConfig(ctx, repo, ociManifest.Config)
+}
+
+func (st *registryStore) pushManifestConfig(ctx context.Context, localStore oras.Target, manifest *Manifest) (ocispec.Descriptor, error) {
+	configBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	configDescriptor := content.NewDescriptorFromBytes(mediaTypeGeneratorConfig, configBytes)
+
+	if err := localStore.Push(ctx, configDescriptor, bytes.NewReader(configBytes)); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return ocispec.Descriptor{}, err
+	}
+
+	return configDescriptor, nil
+}
+
+func fetchManifestConfig(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor) (*Manifest, error) {
+	configBytes, err := content.FetchAll(ctx, fetcher, desc)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Generator{Bin: buf}, nil
+	var manifest Manifest
+	if err := json.Unmarshal(configBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("could not decode generator manifest: %w", err)
+	}
+
+	return &manifest, nil
 }
 
-func (st *registryStore) repoWithSettings(url *url.URL) (*remote.Repository, error) {
-	registrySettings, ok := st.registriesSettings[url.Hostname()]
-	if !ok {
-		registrySettings = defaultRegistrySettings
+// pullRepositories returns the repositories to pull a generator from, in order: the mirrors of its registry first, then the registry.
+func (st *registryStore) pullRepositories(ctx context.Context, url *url.URL) ([]*remote.Repository, error) {
+	settings := st.settings(url.Host)
+
+	var locations []string
+
+	if settings != nil {
+		for _, mirror := range settings.mirrors {
+			locations = append(locations, path.Join(mirror, url.Path))
+		}
 	}
 
-	repo, err := remote.NewRepository(path.Join(url.Host, url.Path))
+	locations = append(locations, settings.repositoryLocation(url.Host, url.Path))
+
+	repos := make([]*remote.Repository, len(locations))
+
+	for i, location := range locations {
+		repo, err := st.repository(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+
+		repos[i] = repo
+	}
+
+	return repos, nil
+}
+
+// repository returns a client for a repository reference, configured with the settings of its registry.
+func (st *registryStore) repository(ctx context.Context, reference string) (*remote.Repository, error) {
+	repo, err := remote.NewRepository(reference)
 	if err != nil {
 		return nil, err
 	}
 
-	repo.PlainHTTP = registrySettings.PlainHTTP
+	client := retry.DefaultClient
+
+	if settings := st.settings(repo.Reference.Registry); settings != nil {
+		repo.PlainHTTP = settings.plainHTTP
+		client = settings.client
+	}
+
+	repo.Client = &auth.Client{
+		Client:     client,
+		Credential: st.credentialsFor(ctx),
+		// Tokens are not shared between calls, as credentials can change from one generator to the other.
+		Cache: auth.NewCache(),
+	}
 
 	return repo, nil
 }
 
+// settings returns the settings of a registry, matching its host:port first and then its hostname only.
+func (st *registryStore) settings(host string) *registrySettings {
+	if settings, ok := st.registries[host]; ok {
+		return settings
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return st.registries[hostname]
+	}
+
+	return nil
+}
+
+// credentialsFor returns the credentials carried by the context, falling back to the ones of the store.
+func (st *registryStore) credentialsFor(ctx context.Context) CredentialFunc {
+	ctxCredentials, _ := credentialsFrom(ctx)
+
+	return ChainCredentials(ctxCredentials, st.credentials)
+}
+
+// IsPinned tells if a generator URL references an immutable generator, that is a registry reference pinned by digest.
+func IsPinned(url *url.URL) bool {
+	if url.Scheme != registryScheme {
+		return false
+	}
+
+	ref, err := registry.ParseReference(path.Join(url.Host, url.Path))
+	if err != nil {
+		return false
+	}
+
+	_, err = ref.Digest()
+
+	return err == nil
+}
+
 func newDescriptorFromGenerator(g *Generator) ocispec.Descriptor {
 	return ocispec.Descriptor{
 		MediaType: mediaTypeWasmLayer,
-		Digest:    digest.FromBytes(g.Bin),
+		Digest:    g.Digest(),
 		Size:      int64(len(g.Bin)),
 	}
 }
 
-func findWasmSuccessor(successors []ocispec.Descriptor) (ocispec.Descriptor, bool) {
+func findSuccessor(successors []ocispec.Descriptor, mediaType string) (ocispec.Descriptor, bool) {
 	for _, s := range successors {
-		if s.MediaType == mediaTypeWasmLayer {
+		if s.MediaType == mediaType {
 			return s, true
 		}
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/jlevesy/dawg/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote"
 )
 
 func TestStore_Registry(t *testing.T) {
@@ -36,5 +37,221 @@ func TestStore_Registry(t *testing.T) {
 	gotGen, err := genStore.Load(ctx, genUrl)
 	require.NoError(t, err)
 
-	assert.Equal(t, &gen, gotGen)
+	assertPulledGenerator(t, &gen, gotGen)
+}
+
+func TestStore_RegistryManifest(t *testing.T) {
+	var (
+		ctx = context.Background()
+		gen = generator.Generator{
+			Bin: []byte("coucou"),
+			Manifest: &generator.Manifest{
+				Name:         "test",
+				Description:  "A test generator",
+				Version:      "v0.0.1",
+				ABIVersion:   "dawg_v1",
+				ConfigSchema: []byte(`{"type":"object"}`),
+				Authors:      []string{"Bob"},
+				SourceURL:    "https://github.com/jlevesy/dawg",
+			},
+		}
+	)
+
+	ts := testutil.RunContainer(t, testutil.RegistryContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, ts.Shutdown(context.Background()))
+	})
+
+	genStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	genUrl, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/test:v0.0.1")
+	require.NoError(t, err)
+
+	err = genStore.Store(ctx, genUrl, &gen)
+	require.NoError(t, err)
+
+	gotGen, err := genStore.Load(ctx, genUrl)
+	require.NoError(t, err)
+
+	assertPulledGenerator(t, &gen, gotGen)
+
+	gotManifest, err := generator.Inspect(ctx, genStore, genUrl)
+	require.NoError(t, err)
+
+	assert.Equal(t, gen.Manifest, gotManifest)
+}
+
+func TestStore_RegistryMirrorsAndRewrites(t *testing.T) {
+	var (
+		ctx = context.Background()
+		gen = generator.Generator{
+			Bin: []byte("coucou"),
+		}
+	)
+
+	ts := testutil.RunContainer(t, testutil.RegistryContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, ts.Shutdown(context.Background()))
+	})
+
+	registryHost := "localhost:" + ts.Port
+
+	genStore, err := generator.DefaultStore(
+		generator.WithRegistriesConfig(
+			&generator.RegistriesConfig{
+				Registries: []generator.RegistryConfig{
+					{Host: "localhost", PlainHTTP: true},
+					// Only reachable through its mirror.
+					{Host: "mirrored.invalid", Mirrors: []string{registryHost + "/mirror"}},
+					{Host: "rewritten.invalid", Location: registryHost + "/mirror"},
+				},
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	genUrl, err := url.Parse("registry://rewritten.invalid/testgenerators/test:v0.0.1")
+	require.NoError(t, err)
+
+	err = genStore.Store(ctx, genUrl, &gen)
+	require.NoError(t, err)
+
+	for _, rawURL := range []string{
+		"registry://" + registryHost + "/mirror/testgenerators/test:v0.0.1",
+		"registry://rewritten.invalid/testgenerators/test:v0.0.1",
+		"registry://mirrored.invalid/testgenerators/test:v0.0.1",
+	} {
+		genUrl, err := url.Parse(rawURL)
+		require.NoError(t, err)
+
+		gotGen, err := genStore.Load(ctx, genUrl)
+		require.NoError(t, err)
+
+		assertPulledGenerator(t, &gen, gotGen)
+	}
+}
+
+func TestStore_RegistryCache(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		cacheDir = t.TempDir()
+		gen      = generator.Generator{
+			Bin: []byte("coucou"),
+		}
+	)
+
+	ts := testutil.RunContainer(t, testutil.RegistryContainerConfig)
+
+	genStore, err := generator.DefaultStore(generator.WithRegistryCacheDir(cacheDir, 1<<20))
+	require.NoError(t, err)
+
+	genUrl, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/test:v0.0.1")
+	require.NoError(t, err)
+
+	err = genStore.Store(ctx, genUrl, &gen)
+	require.NoError(t, err)
+
+	repo, err := remote.NewRepository("localhost:" + ts.Port + "/testgenerators/test:v0.0.1")
+	require.NoError(t, err)
+
+	repo.PlainHTTP = true
+
+	manifestDescriptor, err := repo.Resolve(ctx, "v0.0.1")
+	require.NoError(t, err)
+
+	pinnedURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/test@" + manifestDescriptor.Digest.String())
+	require.NoError(t, err)
+
+	// Pull the generator from a fresh store, to populate the cache from the registry.
+	genStore, err = generator.DefaultStore(generator.WithRegistryCacheDir(cacheDir, 1<<20))
+	require.NoError(t, err)
+
+	gotGen, err := genStore.Load(ctx, genUrl)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+
+	// The registry grants access to the pinned generator, which is served from the cache.
+	gotGen, err = genStore.Load(ctx, pinnedURL)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+
+	otherRepoURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/other@" + manifestDescriptor.Digest.String())
+	require.NoError(t, err)
+
+	// The cache is keyed by digest, but another repository must not serve a generator it does not hold.
+	_, err = genStore.Load(ctx, otherRepoURL)
+	require.Error(t, err)
+
+	require.NoError(t, ts.Shutdown(context.Background()))
+
+	// The registry is gone, but generators it granted access to are still served by the cache.
+	gotGen, err = genStore.Load(ctx, pinnedURL)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+	assert.Equal(t, manifestDescriptor.Digest, gotGen.ResolvedDigest)
+
+	// Tags resolve to the digest they last resolved to.
+	gotGen, err = genStore.Load(ctx, genUrl)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+	assert.Equal(t, manifestDescriptor.Digest, gotGen.ResolvedDigest)
+
+	unresolvedURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/test:v0.0.2")
+	require.NoError(t, err)
+
+	_, err = genStore.Load(ctx, unresolvedURL)
+	require.Error(t, err)
+
+	_, err = genStore.Load(ctx, otherRepoURL)
+	require.Error(t, err)
+
+	// Access is checked against the registry again after a restart.
+	genStore, err = generator.DefaultStore(generator.WithRegistryCacheDir(cacheDir, 1<<20))
+	require.NoError(t, err)
+
+	_, err = genStore.Load(ctx, pinnedURL)
+	require.Error(t, err)
+
+	_, err = genStore.Load(ctx, genUrl)
+	require.Error(t, err)
+}
+
+func assertPulledGenerator(t *testing.T, want, got *generator.Generator) {
+	t.Helper()
+
+	assert.Equal(t, want.Bin, got.Bin)
+	assert.Equal(t, want.Manifest, got.Manifest)
+	assert.NotEmpty(t, got.ResolvedDigest)
+}
+
+func TestIsPinned(t *testing.T) {
+	for _, testCase := range []struct {
+		rawURL string
+		want   bool
+	}{
+		{
+			rawURL: "registry://localhost:5000/generators/test@sha256:62126095f1f8aa90e5c8d9038d5f6801ad3db3b48a2b8e56f848c3c302f78998",
+			want:   true,
+		},
+		{
+			rawURL: "registry://localhost:5000/generators/test:v0.0.1",
+			want:   false,
+		},
+		{
+			rawURL: "registry://localhost:5000/generators/test",
+			want:   false,
+		},
+		{
+			rawURL: "file:///generators/test.wasm",
+			want:   false,
+		},
+	} {
+		t.Run(testCase.rawURL, func(t *testing.T) {
+			genURL, err := url.Parse(testCase.rawURL)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.want, generator.IsPinned(genURL))
+		})
+	}
 }
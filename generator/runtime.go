@@ -11,11 +11,45 @@ import (
 	"github.com/jlevesy/dawg/gdk"
 	"github.com/liamg/memoryfs"
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 type ExecutionResult struct {
+	// Payload is the main output of the generator, it is empty if the generator outputs an envelope.
 	Payload []byte
+	// Resources are the additional resources reported by the generator, either through the host ABI or an output envelope.
+	Resources []Resource
+	// Stdout and Stderr hold what the generator wrote to its standard streams, bounded to the configured log size.
+	Stdout []byte
+	Stderr []byte
+	// Logs are the messages logged by the generator through the host ABI.
+	Logs []LogEntry
+}
+
+// AllResources returns every resource produced by the generator, its main payload being a dashboard.
+func (r *ExecutionResult) AllResources() []Resource {
+	if len(r.Payload) == 0 {
+		return r.Resources
+	}
+
+	return append(
+		[]Resource{{Kind: gdk.ResourceKindDashboard, Payload: r.Payload}},
+		r.Resources...,
+	)
+}
+
+type executionContextKey struct{}
+
+// WithExecutionContext returns a context carrying the facts written to gdk.ContextPath for the generator executed with it.
+// The mount is read-only, so the generator cannot alter them.
+func WithExecutionContext(ctx context.Context, execCtx *gdk.Context) context.Context {
+	return context.WithValue(ctx, executionContextKey{}, execCtx)
+}
+
+func executionContextFrom(ctx context.Context) (*gdk.Context, bool) {
+	execCtx, ok := ctx.Value(executionContextKey{}).(*gdk.Context)
+	return execCtx, ok && execCtx != nil
 }
 
 // Runtime represents any implementation that could execute a given generator with a given payload and retrieve results.
@@ -23,52 +57,252 @@ type Runtime interface {
 	Execute(ctx context.Context, gen *Generator, payload []byte) (*ExecutionResult, error)
 }
 
-func DefaultRuntime(ctx context.Context) (Runtime, func(context.Context) error, error) {
-	// TODO(jly): compilation cache?
-	wasmRuntime := wazero.NewRuntimeWithConfig(
-		ctx,
-		wazero.NewRuntimeConfig().
-			// when the context passed to call expires,
-			// make sure to stop the execution.
-			WithCloseOnContextDone(true),
-	)
+const (
+	defaultModuleCacheSize    = 32
+	defaultExecuteTimeout     = time.Second
+	defaultInstantiateTimeout = time.Second
+	defaultMaxLogSize         = 64 * 1024
+	wasmPageSize              = 65536
+)
+
+// MaxMemoryPages is the maximum amount of memory pages of a generator module, the 4GiB WebAssembly maximum.
+const MaxMemoryPages = 65536
+
+// RuntimeOpt configures the default runtime.
+type RuntimeOpt func(*runtime)
+
+// WithModuleCacheSize sets the maximum amount of compiled modules, and of compiled config schemas, kept in memory.
+// Setting a size lower or equal to zero disables the in memory cache.
+func WithModuleCacheSize(size int) RuntimeOpt {
+	return func(r *runtime) {
+		r.moduleCacheSize = size
+	}
+}
+
+// WithCompilationCacheDir persists the compiled modules into the given directory,
+// allowing to reuse them across runtime restarts.
+func WithCompilationCacheDir(dir string) RuntimeOpt {
+	return func(r *runtime) {
+		r.compilationCacheDir = dir
+	}
+}
+
+// WithInstantiateTimeout sets the maximum duration allowed to compile and instantiate a generator module.
+func WithInstantiateTimeout(timeout time.Duration) RuntimeOpt {
+	return func(r *runtime) {
+		r.instantiateTimeout = timeout
+	}
+}
+
+// WithExecuteTimeout sets the maximum duration allowed for a generator to produce its output.
+func WithExecuteTimeout(timeout time.Duration) RuntimeOpt {
+	return func(r *runtime) {
+		r.executeTimeout = timeout
+	}
+}
+
+// WithMaxMemoryPages caps the linear memory of a generator module, a page is 64KiB.
+// Setting zero keeps the WebAssembly maximum of 65536 pages (4GiB).
+func WithMaxMemoryPages(pages uint32) RuntimeOpt {
+	return func(r *runtime) {
+		r.maxMemoryPages = pages
+	}
+}
+
+// WithMaxOutputSize caps the total size in bytes of the output and resources returned by a generator.
+// Setting zero disables the limit.
+func WithMaxOutputSize(size uint32) RuntimeOpt {
+	return func(r *runtime) {
+		r.maxOutputSize = size
+	}
+}
+
+// WithMaxLogSize caps the amount of bytes captured for each standard stream of a generator.
+func WithMaxLogSize(size int) RuntimeOpt {
+	return func(r *runtime) {
+		r.maxLogSize = size
+	}
+}
+
+func DefaultRuntime(ctx context.Context, opts ...RuntimeOpt) (Runtime, func(context.Context) error, error) {
+	r := runtime{
+		executeTimeout:     defaultExecuteTimeout,
+		instantiateTimeout: defaultInstantiateTimeout,
+		moduleCacheSize:    defaultModuleCacheSize,
+		maxLogSize:         defaultMaxLogSize,
+	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+
+	if r.maxMemoryPages > MaxMemoryPages {
+		return nil, nil, fmt.Errorf("max memory pages can't exceed %d, got %d", MaxMemoryPages, r.maxMemoryPages)
+	}
 
-	if _, err := wasi_snapshot_preview1.Instantiate(ctx, wasmRuntime); err != nil {
+	wasmConfig := wazero.NewRuntimeConfig().
+		// when the context passed to call expires,
+		// make sure to stop the execution.
+		WithCloseOnContextDone(true)
+
+	var compilationCache wazero.CompilationCache
+
+	if r.compilationCacheDir != "" {
+		var err error
+
+		compilationCache, err = wazero.NewCompilationCacheWithDir(r.compilationCacheDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not setup compilation cache: %w", err)
+		}
+
+		wasmConfig = wasmConfig.WithCompilationCache(compilationCache)
+	}
+
+	r.wasm = wazero.NewRuntimeWithConfig(ctx, wasmConfig)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r.wasm); err != nil {
+		return nil, nil, err
+	}
+
+	if err := instantiateHostModule(ctx, r.wasm); err != nil {
 		return nil, nil, err
 	}
 
-	return &runtime{
-		wasm: wasmRuntime,
-		// TODO configure.
-		executeTimeout:     time.Second,
-		instantiateTimeout: time.Second,
-	}, wasmRuntime.Close, nil
+	if r.moduleCacheSize > 0 {
+		r.modules = newModuleCache(r.moduleCacheSize)
+		r.schemas = newSchemaCache(r.moduleCacheSize)
+	}
+
+	return &r, func(ctx context.Context) error {
+		if r.modules != nil {
+			if err := r.modules.Close(); err != nil {
+				return err
+			}
+		}
+
+		if err := r.wasm.Close(ctx); err != nil {
+			return err
+		}
+
+		if compilationCache != nil {
+			return compilationCache.Close(ctx)
+		}
+
+		return nil
+	}, nil
 }
 
 type runtime struct {
 	wasm               wazero.Runtime
 	executeTimeout     time.Duration
 	instantiateTimeout time.Duration
+	maxMemoryPages     uint32
+	maxOutputSize      uint32
+	maxLogSize         int
+
+	modules             *moduleCache
+	schemas             *schemaCache
+	moduleCacheSize     int
+	compilationCacheDir string
 }
 
 func (r *runtime) Execute(ctx context.Context, gen *Generator, payload []byte) (*ExecutionResult, error) {
+	// Catch config mistakes early with precise errors, instead of a vague generator failure.
+	if err := r.validateConfig(gen.Manifest, payload); err != nil {
+		return nil, err
+	}
+
+	state := executionState{
+		maxOutputSize: r.maxOutputSize,
+		maxLogSize:    r.maxLogSize,
+		stdout:        boundedBuffer{max: r.maxLogSize},
+		stderr:        boundedBuffer{max: r.maxLogSize},
+	}
+
+	resultBuf, err := r.execute(withExecutionState(ctx, &state), gen, payload, &state)
+	if err != nil {
+		return nil, state.executionError(err)
+	}
+
+	resources, isEnvelope, err := decodeEnvelope(resultBuf)
+	if err != nil {
+		return nil, state.executionError(err)
+	}
+
+	if isEnvelope {
+		resultBuf = nil
+	}
+
+	return &ExecutionResult{
+		Payload:   resultBuf,
+		Resources: append(state.resources, resources...),
+		Stdout:    state.stdout.Bytes(),
+		Stderr:    state.stderr.Bytes(),
+		Logs:      state.logs,
+	}, nil
+}
+
+func (r *runtime) execute(ctx context.Context, gen *Generator, payload []byte, state *executionState) ([]byte, error) {
 	fs := memoryfs.New()
 
 	if err := fs.WriteFile(filepath.Base(gdk.InputPath), payload, 0o600); err != nil {
 		return nil, fmt.Errorf("could not write config file %w", err)
 	}
 
+	if execCtx, ok := executionContextFrom(ctx); ok {
+		b, err := json.Marshal(execCtx)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode context file %w", err)
+		}
+
+		if err := fs.WriteFile(filepath.Base(gdk.ContextPath), b, 0o400); err != nil {
+			return nil, fmt.Errorf("could not write context file %w", err)
+		}
+	}
+
 	instanciateCtx, cancel := context.WithTimeout(ctx, r.instantiateTimeout)
 	defer cancel()
 
-	mod, err := r.wasm.InstantiateWithConfig(
+	compiled, release, err := r.compile(instanciateCtx, gen)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile generator module: %w", err)
+	}
+
+	defer release(ctx)
+
+	// The memory limit is enforced by the allocator of the module memory, which knows when a grow goes over it.
+	var limiter *memoryLimiter
+
+	if r.maxMemoryPages > 0 {
+		if err := checkDeclaredMemory(compiled, r.maxMemoryPages); err != nil {
+			return nil, &MemoryLimitError{MaxPages: r.maxMemoryPages, Err: err}
+		}
+
+		limiter = newMemoryLimiter(r.maxMemoryPages)
+		instanciateCtx = experimental.WithMemoryAllocator(instanciateCtx, limiter)
+	}
+
+	mod, err := r.wasm.InstantiateModule(
 		instanciateCtx,
-		gen.Bin,
-		wazero.NewModuleConfig().WithFSConfig(
-			wazero.NewFSConfig().WithFSMount(fs, "/dawg"),
-		),
+		compiled,
+		wazero.NewModuleConfig().
+			// Clear the module name, the same compiled module can be instantiated concurrently.
+			WithName("").
+			WithStdout(&state.stdout).
+			WithStderr(&state.stderr).
+			WithFSConfig(
+				wazero.NewFSConfig().WithFSMount(fs, "/dawg"),
+			),
 	)
 	if err != nil {
+		if isDeadlineExceeded(ctx, instanciateCtx) {
+			return nil, &InstantiateTimeoutError{Timeout: r.instantiateTimeout, Err: err}
+		}
+
+		if limiter.Exceeded() {
+			return nil, &MemoryLimitError{MaxPages: r.maxMemoryPages, Err: err}
+		}
+
 		return nil, fmt.Errorf("could not instantiate generator module: %w", err)
 	}
 
@@ -77,6 +311,10 @@ func (r *runtime) Execute(ctx context.Context, gen *Generator, payload []byte) (
 		_ = mod.Close(ctx)
 	}()
 
+	if limiter.Exceeded() {
+		return nil, &MemoryLimitError{MaxPages: r.maxMemoryPages, Err: errMemoryLimit}
+	}
+
 	fn := mod.ExportedFunction("generate")
 	if fn == nil {
 		return nil, unexportedSymbolError("generate")
@@ -87,12 +325,42 @@ func (r *runtime) Execute(ctx context.Context, gen *Generator, payload []byte) (
 
 	fnResult, err := fn.Call(callCtx)
 	if err != nil {
+		if isDeadlineExceeded(ctx, callCtx) {
+			return nil, &ExecuteTimeoutError{Timeout: r.executeTimeout, Err: err}
+		}
+
+		if limiter.Exceeded() {
+			return nil, &MemoryLimitError{MaxPages: r.maxMemoryPages, Err: err}
+		}
+
 		return nil, fmt.Errorf("call to function generate reported  an error: %w", err)
 	}
 
+	if state.hostErr != nil {
+		return nil, state.hostErr
+	}
+
+	// Generators using the host ABI report their results through host calls.
+	if state.reported() {
+		if state.errPayload != nil {
+			return nil, decodeRuntimeError(state.errPayload)
+		}
+
+		return state.output, nil
+	}
+
+	if len(fnResult) == 0 {
+		return nil, errNoOutput
+	}
+
+	// Legacy ABI, generate returns the output location packed in an uint64.
 	// This limits the payload size to 4Gb.
 	resultBufPtr, resultBufSize := uint32(fnResult[0]>>32), uint32(fnResult[0])
 
+	if r.maxOutputSize > 0 && resultBufSize > r.maxOutputSize {
+		return nil, &OutputSizeError{Size: uint64(resultBufSize), MaxSize: r.maxOutputSize}
+	}
+
 	resultBuf, ok := mod.Memory().Read(resultBufPtr, resultBufSize)
 	if !ok {
 		return nil, errFailedToReadMemory
@@ -100,24 +368,69 @@ func (r *runtime) Execute(ctx context.Context, gen *Generator, payload []byte) (
 
 	// This is a very dumb and naive way of reporing an error.
 	// But at least comporate the first byte efficiently, do not try to json unmarshal every result.
-	// TODO(jly): do better.
 	if len(resultBuf) > 0 && resultBuf[0] == 'e' {
-		var gdkErr gdk.RuntimeError
+		return nil, decodeRuntimeError(resultBuf[1:])
+	}
+
+	return resultBuf, nil
+}
+
+// validateConfig validates a config against the schema of the generator, reusing the compiled schema if cached.
+func (r *runtime) validateConfig(manifest *Manifest, config []byte) error {
+	if r.schemas == nil {
+		return ValidateConfig(manifest, config)
+	}
+
+	return r.schemas.validate(manifest, config)
+}
+
+// decodeRuntimeError decodes an error reported by a generator as a *gdk.RuntimeError.
+func decodeRuntimeError(payload []byte) error {
+	var gdkErr gdk.RuntimeError
+
+	if err := json.Unmarshal(payload, &gdkErr); err != nil {
+		return fmt.Errorf("generator reported an error but we could not deserialize it: %w", err)
+	}
+
+	return &gdkErr
+}
 
-		if err := json.Unmarshal(resultBuf[1:], &gdkErr); err != nil {
-			return nil, fmt.Errorf("generator reported an error but we could not deserialize it: %w", err)
+// compile returns the compiled module of a generator, reusing a cached one if available.
+// The returned release func must be called once the caller is done with the compiled module.
+func (r *runtime) compile(ctx context.Context, gen *Generator) (wazero.CompiledModule, func(context.Context), error) {
+	if r.modules == nil {
+		compiled, err := r.wasm.CompileModule(ctx, gen.Bin)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		return nil, errors.New(gdkErr.Err)
+		return compiled, func(ctx context.Context) { _ = compiled.Close(ctx) }, nil
 	}
 
-	return &ExecutionResult{
-		Payload: resultBuf,
-	}, nil
+	dgst := gen.Digest()
+
+	if compiled, release, ok := r.modules.Get(dgst); ok {
+		return compiled, release, nil
+	}
+
+	compiled, err := r.wasm.CompileModule(ctx, gen.Bin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiled, release := r.modules.Add(ctx, dgst, compiled)
+
+	return compiled, release, nil
+}
+
+// isDeadlineExceeded tells if the given context has timed out while its parent is still alive.
+func isDeadlineExceeded(parent, ctx context.Context) bool {
+	return parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
 }
 
 var (
 	errFailedToReadMemory = errors.New("could not read to the module memory")
+	errNoOutput           = errors.New("generator did not report any output")
 )
 
 type unexportedSymbolError string
@@ -125,3 +438,73 @@ type unexportedSymbolError string
 func (u unexportedSymbolError) Error() string {
 	return fmt.Sprintf("module does not export the function %q", string(u))
 }
+
+// ExecutionError is returned when a generator execution fails.
+// It carries what the generator wrote to its standard streams and logged before failing.
+type ExecutionError struct {
+	Stdout []byte
+	Stderr []byte
+	Logs   []LogEntry
+	Err    error
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// InstantiateTimeoutError is returned when a generator module could not be instantiated in time.
+type InstantiateTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *InstantiateTimeoutError) Error() string {
+	return fmt.Sprintf("generator instantiation exceeded the timeout of %s: %s", e.Timeout, e.Err)
+}
+
+func (e *InstantiateTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteTimeoutError is returned when a generator did not produce its output in time.
+type ExecuteTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *ExecuteTimeoutError) Error() string {
+	return fmt.Sprintf("generator execution exceeded the timeout of %s: %s", e.Timeout, e.Err)
+}
+
+func (e *ExecuteTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// MemoryLimitError is returned when a generator requires more memory than allowed.
+type MemoryLimitError struct {
+	MaxPages uint32
+	Err      error
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("generator exceeded the memory limit of %d pages: %s", e.MaxPages, e.Err)
+}
+
+func (e *MemoryLimitError) Unwrap() error {
+	return e.Err
+}
+
+// OutputSizeError is returned when a generator produces a payload bigger than allowed.
+// Size is the total size of the output and resources reported when going over the limit.
+type OutputSizeError struct {
+	Size    uint64
+	MaxSize uint32
+}
+
+func (e *OutputSizeError) Error() string {
+	return fmt.Sprintf("generator output of %d bytes exceeds the limit of %d bytes", e.Size, e.MaxSize)
+}
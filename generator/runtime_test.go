@@ -3,11 +3,16 @@ package generator_test
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jlevesy/dawg/gdk"
 	"github.com/jlevesy/dawg/generator"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -147,11 +152,355 @@ func TestRuntime_Hang(t *testing.T) {
 	_, err := runWasm(t, hangBin, nil)
 	// wazero doesn't exactly wrap this error as far as I can see.
 	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+
+	var timeoutErr *generator.ExecuteTimeoutError
+	assert.ErrorAs(t, err, &timeoutErr)
+
+	var execErr *generator.ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Contains(t, string(execErr.Stdout), "poll poll poll, am bad.")
 }
 
-func runWasm(t *testing.T, bin, args []byte) (*generator.ExecutionResult, error) {
-	t.Helper()
+func TestRuntime_ExecuteTimeout(t *testing.T) {
+	_, err := runWasm(t, hangBin, nil, generator.WithExecuteTimeout(100*time.Millisecond))
+
+	var timeoutErr *generator.ExecuteTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, 100*time.Millisecond, timeoutErr.Timeout)
+}
+
+//go:generate tinygo build -o ./testdata/runtime/oom.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/oom
+//go:embed testdata/runtime/oom.wasm
+var oomBin []byte
+
+func TestRuntime_MaxMemoryPages(t *testing.T) {
+	_, err := runWasm(t, oomBin, nil, generator.WithMaxMemoryPages(64))
+
+	var memErr *generator.MemoryLimitError
+	require.ErrorAs(t, err, &memErr)
+	assert.Equal(t, uint32(64), memErr.MaxPages)
+}
+
+func TestRuntime_MaxMemoryPagesOnlyReportsFailedGrows(t *testing.T) {
+	var (
+		// i32.const 10, memory.grow, drop, i64.const 0
+		growBody = []byte{0x41, 0x0a, 0x40, 0x00, 0x1a, 0x42, 0x00}
+		// unreachable
+		trapBody = []byte{0x00}
+	)
+
+	for _, tt := range []struct {
+		desc          string
+		bin           []byte
+		wantMemoryErr bool
+	}{
+		{
+			desc:          "grows over the limit",
+			bin:           wasmModule(1, growBody),
+			wantMemoryErr: true,
+		},
+		{
+			desc:          "declares more memory than allowed",
+			bin:           wasmModule(8, trapBody),
+			wantMemoryErr: true,
+		},
+		{
+			desc:          "traps close to the limit",
+			bin:           wasmModule(3, trapBody),
+			wantMemoryErr: false,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := runWasm(t, tt.bin, nil, generator.WithMaxMemoryPages(4))
+			require.Error(t, err)
+
+			var memErr *generator.MemoryLimitError
+			assert.Equal(t, tt.wantMemoryErr, errors.As(err, &memErr), err)
+		})
+	}
+}
+
+// wasmModule builds a module exporting a memory of minPages, and a generate function running body.
+func wasmModule(minPages byte, body []byte) []byte {
+	code := append(append([]byte{0x00}, body...), 0x0b)
+
+	bin := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		// type section: func() i64
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7e,
+		// function section
+		0x03, 0x02, 0x01, 0x00,
+		// memory section
+		0x05, 0x03, 0x01, 0x00, minPages,
+		// export section: memory and generate
+		0x07, 0x15, 0x02,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x08, 'g', 'e', 'n', 'e', 'r', 'a', 't', 'e', 0x00, 0x00,
+		// code section
+		0x0a, byte(len(code) + 2), 0x01, byte(len(code)),
+	}
+
+	return append(bin, code...)
+}
+
+func TestRuntime_MaxOutputSize(t *testing.T) {
+	_, err := runWasm(t, goodTinygo, []byte(`{"some":"config"}`), generator.WithMaxOutputSize(4))
+
+	var sizeErr *generator.OutputSizeError
+	require.ErrorAs(t, err, &sizeErr)
+	assert.Equal(t, uint64(17), sizeErr.Size)
+	assert.Equal(t, uint32(4), sizeErr.MaxSize)
+}
+
+func TestRuntime_MaxOutputSizeCountsAllResources(t *testing.T) {
+	for _, tt := range []struct {
+		desc        string
+		calls       int
+		wantSizeErr bool
+	}{
+		{
+			desc:  "resources within the limit",
+			calls: 4,
+		},
+		{
+			desc:        "resources going over the limit",
+			calls:       5,
+			wantSizeErr: true,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			// Each call adds a resource of 4 bytes, smaller than the limit.
+			result, err := runWasm(t, addResourcesModule(tt.calls, 4), nil, generator.WithMaxOutputSize(16))
+
+			if !tt.wantSizeErr {
+				require.NoError(t, err)
+				assert.Len(t, result.Resources, tt.calls)
+				return
+			}
+
+			var sizeErr *generator.OutputSizeError
+			require.ErrorAs(t, err, &sizeErr)
+			assert.Equal(t, uint64(20), sizeErr.Size)
+			assert.Equal(t, uint32(16), sizeErr.MaxSize)
+		})
+	}
+}
+
+// addResourcesModule builds a module whose generate function adds calls resources of size bytes through the host ABI.
+func addResourcesModule(calls int, size byte) []byte {
+	code := []byte{0x00}
+	for i := 0; i < calls; i++ {
+		// i32.const 0 (kind ptr), i32.const 0 (kind size), i32.const 0 (ptr), i32.const size, call add_resource
+		code = append(code, 0x41, 0x00, 0x41, 0x00, 0x41, 0x00, 0x41, size, 0x10, 0x00)
+	}
+	// i64.const 0, end
+	code = append(code, 0x42, 0x00, 0x0b)
+
+	bin := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		// type section: func() i64 and func(i32, i32, i32, i32)
+		0x01, 0x0c, 0x02,
+		0x60, 0x00, 0x01, 0x7e,
+		0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x00,
+		// import section: dawg_v1.add_resource
+		0x02, 0x18, 0x01,
+		0x07, 'd', 'a', 'w', 'g', '_', 'v', '1',
+		0x0c, 'a', 'd', 'd', '_', 'r', 'e', 's', 'o', 'u', 'r', 'c', 'e', 0x00, 0x01,
+		// function section
+		0x03, 0x02, 0x01, 0x00,
+		// memory section
+		0x05, 0x03, 0x01, 0x00, 0x01,
+		// export section: memory and generate
+		0x07, 0x15, 0x02,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x08, 'g', 'e', 'n', 'e', 'r', 'a', 't', 'e', 0x00, 0x01,
+		// code section
+		0x0a, byte(len(code) + 2), 0x01, byte(len(code)),
+	}
+
+	return append(bin, code...)
+}
+
+func TestRuntime_CachesCompiledModules(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		cacheDir = t.TempDir()
+		payload  = []byte(`{"some":"config"}`)
+	)
+
+	runtime, shutdown, err := generator.DefaultRuntime(
+		ctx,
+		generator.WithModuleCacheSize(1),
+		generator.WithCompilationCacheDir(cacheDir),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := shutdown(ctx)
+		require.NoError(t, err)
+	})
+
+	for i := 0; i < 3; i++ {
+		result, err := runtime.Execute(ctx, &generator.Generator{Bin: goodTinygo}, payload)
+		require.NoError(t, err)
+
+		assert.Equal(t, payload, result.Payload)
+	}
+
+	// Evicts the good module from the in memory cache.
+	_, err = runtime.Execute(ctx, &generator.Generator{Bin: panicBin}, nil)
+	assert.Error(t, err)
+
+	result, err := runtime.Execute(ctx, &generator.Generator{Bin: goodTinygo}, payload)
+	require.NoError(t, err)
+
+	assert.Equal(t, payload, result.Payload)
+
+	cachedFiles, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cachedFiles, "Compiled modules should have been persisted")
+}
+
+func TestRuntime_EvictsCompiledModulesInUse(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		payload = []byte(`{"some":"config"}`)
+	)
+
+	// A single entry, so that concurrent executions keep evicting the module the others are using.
+	runtime, shutdown, err := generator.DefaultRuntime(ctx, generator.WithModuleCacheSize(1))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := shutdown(ctx)
+		require.NoError(t, err)
+	})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				result, err := runtime.Execute(ctx, &generator.Generator{Bin: goodTinygo}, payload)
+				if assert.NoError(t, err) {
+					assert.Equal(t, payload, result.Payload)
+				}
+
+				return
+			}
+
+			_, err := runtime.Execute(ctx, &generator.Generator{Bin: envelopeBin}, nil)
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+//go:generate tinygo build -o ./testdata/runtime/logs.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/logs
+//go:embed testdata/runtime/logs.wasm
+var logsBin []byte
+
+func TestRuntime_CapturesOutput(t *testing.T) {
+	result, err := runWasm(t, logsBin, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello from stdout\n", string(result.Stdout))
+	assert.Equal(t, "hello from stderr\n", string(result.Stderr))
+	assert.Equal(t, `{}`, string(result.Payload))
+}
+
+func TestRuntime_BoundsCapturedOutput(t *testing.T) {
+	result, err := runWasm(t, logsBin, nil, generator.WithMaxLogSize(5))
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello", string(result.Stdout))
+	assert.Equal(t, "hello", string(result.Stderr))
+}
+
+//go:generate tinygo build -o ./testdata/runtime/hostabi.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/hostabi
+//go:embed testdata/runtime/hostabi.wasm
+var hostABIBin []byte
+
+func TestRuntime_HostABI(t *testing.T) {
+	var payload = []byte(`{"some":"config"}`)
+
+	result, err := runWasm(t, hostABIBin, payload)
+	require.NoError(t, err)
+
+	assert.Equal(t, payload, result.Payload)
+	assert.Equal(
+		t,
+		[]generator.Resource{{Kind: "alert-rule", Payload: []byte(`{"title":"rule"}`)}},
+		result.Resources,
+	)
+	assert.Equal(
+		t,
+		[]generator.LogEntry{{Level: gdk.LogLevelInfo, Message: "generating"}},
+		result.Logs,
+	)
+}
+
+//go:generate tinygo build -o ./testdata/runtime/hostabierror.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/hostabierror
+//go:embed testdata/runtime/hostabierror.wasm
+var hostABIErrorBin []byte
 
+func TestRuntime_HostABIReportsError(t *testing.T) {
+	_, err := runWasm(t, hostABIErrorBin, nil)
+	require.EqualError(t, err, "something went wrong")
+
+	var execErr *generator.ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(
+		t,
+		[]generator.LogEntry{{Level: gdk.LogLevelError, Message: "about to fail"}},
+		execErr.Logs,
+	)
+}
+
+//go:generate tinygo build -o ./testdata/runtime/invalidconfig.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/invalidconfig
+//go:embed testdata/runtime/invalidconfig.wasm
+var invalidConfigBin []byte
+
+func TestRuntime_ReportsStructuredError(t *testing.T) {
+	_, err := runWasm(t, invalidConfigBin, nil)
+
+	var gdkErr *gdk.RuntimeError
+	require.ErrorAs(t, err, &gdkErr)
+	assert.Equal(t, gdk.ErrorCodeInvalidConfig, gdkErr.Code)
+	assert.Equal(t, "app_name", gdkErr.Field)
+	assert.False(t, gdkErr.Retryable())
+	assert.EqualError(t, err, `invalid field "app_name": must not be empty`)
+}
+
+//go:generate tinygo build -o ./testdata/runtime/envelope.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/envelope
+//go:embed testdata/runtime/envelope.wasm
+var envelopeBin []byte
+
+func TestRuntime_OutputEnvelope(t *testing.T) {
+	result, err := runWasm(t, envelopeBin, nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Payload)
+	assert.Equal(
+		t,
+		[]generator.Resource{
+			{Kind: gdk.ResourceKindDashboard, Payload: []byte(`{"title":"dashboard"}`)},
+			{Kind: gdk.ResourceKindAlertRule, Payload: []byte(`{"uid":"rule"}`)},
+		},
+		result.AllResources(),
+	)
+}
+
+//go:generate tinygo build -o ./testdata/runtime/context.wasm -scheduler=none --no-debug -target wasi ./testdata/runtime/context
+//go:embed testdata/runtime/context.wasm
+var contextBin []byte
+
+func TestRuntime_ExecutionContext(t *testing.T) {
 	ctx := context.Background()
 	runtime, shutdown, err := generator.DefaultRuntime(ctx)
 	require.NoError(t, err)
@@ -161,5 +510,150 @@ func runWasm(t *testing.T, bin, args []byte) (*generator.ExecutionResult, error)
 		require.NoError(t, err)
 	})
 
+	genCtx := gdk.Context{
+		Grafana: &gdk.GrafanaContext{
+			Version:     "10.2.0",
+			Datasources: []gdk.DatasourceContext{{UID: "prom", Name: "Prometheus", Type: "prometheus", IsDefault: true}},
+		},
+		Resource: &gdk.ResourceContext{
+			Kind:      "Dashboard",
+			Namespace: "default",
+			Name:      "dashboard",
+			Labels:    map[string]string{"team": "foo"},
+		},
+	}
+
+	result, err := runtime.Execute(generator.WithExecutionContext(ctx, &genCtx), &generator.Generator{Bin: contextBin}, nil)
+	require.NoError(t, err)
+
+	var gotCtx gdk.Context
+	require.NoError(t, json.Unmarshal(result.Payload, &gotCtx))
+	assert.Equal(t, genCtx, gotCtx)
+
+	// Without a context, the generator fails reading it.
+	_, err = runtime.Execute(ctx, &generator.Generator{Bin: contextBin}, nil)
+	assert.ErrorContains(t, err, gdk.ErrNoContext.Error())
+}
+
+func TestRuntime_ValidatesConfig(t *testing.T) {
+	manifest := generator.Manifest{
+		Name: "test",
+		ConfigSchema: []byte(`{
+			"type": "object",
+			"required": ["app_name"],
+			"properties": {
+				"app_name": {"type": "string", "minLength": 1},
+				"panels": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {"height": {"type": "integer"}}
+					}
+				}
+			}
+		}`),
+	}
+
+	for _, testCase := range []struct {
+		desc           string
+		config         string
+		wantViolations []generator.ConfigViolation
+	}{
+		{
+			desc:   "invalid field",
+			config: "app_name: \"\"",
+			wantViolations: []generator.ConfigViolation{
+				{Field: "app_name", Message: "length must be >= 1, but got 0"},
+			},
+		},
+		{
+			desc:   "invalid nested field",
+			config: "app_name: foo\npanels:\n- height: big",
+			wantViolations: []generator.ConfigViolation{
+				{Field: "panels[0].height", Message: "expected integer, but got string"},
+			},
+		},
+		{
+			desc:   "missing field",
+			config: "{}",
+			wantViolations: []generator.ConfigViolation{
+				{Message: "missing properties: 'app_name'"},
+			},
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			ctx := context.Background()
+			runtime, shutdown, err := generator.DefaultRuntime(ctx)
+			require.NoError(t, err)
+
+			t.Cleanup(func() {
+				err := shutdown(ctx)
+				require.NoError(t, err)
+			})
+
+			// The binary is not a valid WASM module, it must not be compiled when the config is invalid.
+			_, err = runtime.Execute(
+				ctx,
+				&generator.Generator{Bin: []byte("not wasm"), Manifest: &manifest},
+				[]byte(testCase.config),
+			)
+
+			var configErr *generator.ConfigValidationError
+			require.ErrorAs(t, err, &configErr)
+			assert.Equal(t, testCase.wantViolations, configErr.Violations)
+		})
+	}
+}
+
+func TestRuntime_ValidatesConfigWithCachedSchemas(t *testing.T) {
+	ctx := context.Background()
+	runtime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := shutdown(ctx)
+		require.NoError(t, err)
+	})
+
+	var (
+		intManifest = generator.Manifest{
+			Name:         "test",
+			ConfigSchema: []byte(`{"type":"object","properties":{"some":{"type":"integer"}}}`),
+		}
+		stringManifest = generator.Manifest{
+			Name:         "test",
+			ConfigSchema: []byte(`{"type":"object","properties":{"some":{"type":"string"}}}`),
+		}
+		configErr *generator.ConfigValidationError
+	)
+
+	// The compiled schema is reused by the following executions.
+	for i := 0; i < 2; i++ {
+		_, err = runtime.Execute(ctx, &generator.Generator{Bin: []byte("not wasm"), Manifest: &intManifest}, []byte("some: foo"))
+		require.ErrorAs(t, err, &configErr)
+		assert.Equal(t, "some", configErr.Violations[0].Field)
+	}
+
+	// The same binary shipped with another schema is validated against it.
+	_, err = runtime.Execute(ctx, &generator.Generator{Bin: []byte("not wasm"), Manifest: &stringManifest}, []byte("some: foo"))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &configErr))
+
+	_, err = runtime.Execute(ctx, &generator.Generator{Bin: []byte("not wasm"), Manifest: &stringManifest}, []byte("some: 1"))
+	require.ErrorAs(t, err, &configErr)
+}
+
+func runWasm(t *testing.T, bin, args []byte, opts ...generator.RuntimeOpt) (*generator.ExecutionResult, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	runtime, shutdown, err := generator.DefaultRuntime(ctx, opts...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := shutdown(ctx)
+		require.NoError(t, err)
+	})
+
 	return runtime.Execute(ctx, &generator.Generator{Bin: bin}, args)
 }
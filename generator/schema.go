@@ -0,0 +1,213 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jlevesy/dawg/internal/lru"
+	"github.com/opencontainers/go-digest"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"sigs.k8s.io/yaml"
+)
+
+const configSchemaURL = "dawg://generator/config-schema.json"
+
+// ConfigViolation is a single violation of the generator config schema.
+type ConfigViolation struct {
+	// Field is the path of the offending field, app.replicas[0] for instance. It is empty for the config root.
+	Field   string
+	Message string
+}
+
+func (v ConfigViolation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+
+	return fmt.Sprintf("invalid field %q: %s", v.Field, v.Message)
+}
+
+// ConfigValidationError is returned when the config does not match the schema shipped with the generator.
+type ConfigValidationError struct {
+	Violations []ConfigViolation
+}
+
+func (e *ConfigValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+
+	return "invalid config: " + strings.Join(msgs, ", ")
+}
+
+// ValidateConfig validates a YAML or JSON config against the schema of the generator manifest, if any.
+func ValidateConfig(manifest *Manifest, config []byte) error {
+	if manifest == nil || len(manifest.ConfigSchema) == 0 {
+		return nil
+	}
+
+	schema, err := compileConfigSchema(manifest.ConfigSchema)
+	if err != nil {
+		return err
+	}
+
+	return validateConfig(schema, config)
+}
+
+func compileConfigSchema(rawSchema []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	// Schemas must be self contained, never reach out to the filesystem or the network.
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("could not load %q: external references are not supported", s)
+	}
+
+	if err := compiler.AddResource(configSchemaURL, bytes.NewReader(rawSchema)); err != nil {
+		return nil, fmt.Errorf("invalid generator config schema: %w", err)
+	}
+
+	schema, err := compiler.Compile(configSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generator config schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+func validateConfig(schema *jsonschema.Schema, config []byte) error {
+	configJSON, err := yaml.YAMLToJSON(config)
+	if err != nil {
+		return &ConfigValidationError{
+			Violations: []ConfigViolation{{Message: err.Error()}},
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(configJSON))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &ConfigValidationError{
+			Violations: []ConfigViolation{{Message: err.Error()}},
+		}
+	}
+
+	var validationErr *jsonschema.ValidationError
+
+	err = schema.Validate(value)
+	switch {
+	case errors.As(err, &validationErr):
+		return &ConfigValidationError{Violations: configViolations(validationErr)}
+	case err != nil:
+		return err
+	default:
+		return nil
+	}
+}
+
+// schemaCache keeps the most recently used compiled config schemas in memory, indexed by the digest of the schema.
+// Generators shipping the same schema share its compiled version.
+type schemaCache struct {
+	mu      sync.Mutex
+	schemas *lru.Cache[digest.Digest, *jsonschema.Schema]
+}
+
+func newSchemaCache(maxEntries int) *schemaCache {
+	return &schemaCache{schemas: lru.New[digest.Digest, *jsonschema.Schema](maxEntries, nil)}
+}
+
+// validate validates a config against the schema of the generator manifest, compiling the schema only once.
+func (c *schemaCache) validate(manifest *Manifest, config []byte) error {
+	if manifest == nil || len(manifest.ConfigSchema) == 0 {
+		return nil
+	}
+
+	schema, err := c.compile(manifest.ConfigSchema)
+	if err != nil {
+		return err
+	}
+
+	return validateConfig(schema, config)
+}
+
+func (c *schemaCache) compile(rawSchema []byte) (*jsonschema.Schema, error) {
+	dgst := digest.FromBytes(rawSchema)
+
+	c.mu.Lock()
+	schema, ok := c.schemas.Get(dgst)
+	c.mu.Unlock()
+
+	if ok {
+		return schema, nil
+	}
+
+	// Invalid schemas are not cached, the generator fails before running anyway.
+	schema, err := compileConfigSchema(rawSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.schemas.Get(dgst); ok {
+		return cached, nil
+	}
+
+	c.schemas.Add(dgst, schema)
+
+	return schema, nil
+}
+
+// configViolations flattens the leaves of a validation error, they are the ones carrying an actionable message.
+func configViolations(err *jsonschema.ValidationError) []ConfigViolation {
+	if len(err.Causes) == 0 {
+		return []ConfigViolation{
+			{
+				Field:   fieldPath(err.InstanceLocation),
+				Message: err.Message,
+			},
+		}
+	}
+
+	var violations []ConfigViolation
+
+	for _, cause := range err.Causes {
+		violations = append(violations, configViolations(cause)...)
+	}
+
+	return violations
+}
+
+// fieldPath converts a JSON pointer into a field path: /app/replicas/0 becomes app.replicas[0].
+func fieldPath(pointer string) string {
+	if pointer == "" {
+		return ""
+	}
+
+	var path strings.Builder
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		if _, err := strconv.Atoi(token); err == nil {
+			path.WriteString("[" + token + "]")
+			continue
+		}
+
+		if path.Len() > 0 {
+			path.WriteByte('.')
+		}
+
+		path.WriteString(token)
+	}
+
+	return path.String()
+}
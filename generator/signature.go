@@ -0,0 +1,275 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// Signatures follow their own format, described in the README so that generators can be signed by other tools.
+const (
+	artifactTypeSignature     = "application/vnd.dawg.generator.signature.v1"
+	mediaTypeSignaturePayload = "application/vnd.dawg.generator.signature.payload.v1+json"
+	annotationSignature       = "cc.urcloud.dawg.signature"
+)
+
+// ErrUnverifiedGenerator is returned when signature verification is enabled and a generator has no valid signature.
+var ErrUnverifiedGenerator = errors.New("generator signature could not be verified")
+
+// Signer allows to sign a generator already stored at an URL.
+type Signer interface {
+	Sign(ctx context.Context, url *url.URL, key crypto.Signer) error
+}
+
+// Sign signs the generator stored at the given URL, for instance a generator pushed by another tool.
+func Sign(ctx context.Context, w Writer, url *url.URL, key crypto.Signer) error {
+	signer, ok := w.(Signer)
+	if !ok {
+		return fmt.Errorf("generator %s can't be signed", url)
+	}
+
+	return signer.Sign(ctx, url, key)
+}
+
+// signaturePayload is what gets signed, it binds the signature to a generator OCI manifest.
+type signaturePayload struct {
+	Generator ocispec.Descriptor `json:"generator"`
+}
+
+// LoadSigningKey reads a PEM encoded ECDSA, Ed25519 or RSA private key, in PKCS#8, SEC 1 or PKCS#1 form.
+func LoadSigningKey(path string) (crypto.Signer, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %q", path)
+	}
+
+	var key any
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key %q: %w", path, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	return signer, nil
+}
+
+// LoadVerificationKeys reads a bundle of PEM encoded public keys.
+func LoadVerificationKeys(path string) ([]crypto.PublicKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []crypto.PublicKey
+
+	for {
+		var block *pem.Block
+
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse verification key in %q: %w", path, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public key found in %q", path)
+	}
+
+	return keys, nil
+}
+
+// pushSignature signs a generator manifest, and pushes the signature to the repository as a referrer of the manifest.
+func pushSignature(ctx context.Context, repo *remote.Repository, key crypto.Signer, subject ocispec.Descriptor) error {
+	payload, err := json.Marshal(signaturePayload{
+		Generator: ocispec.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	signature, err := sign(key, payload)
+	if err != nil {
+		return fmt.Errorf("could not sign generator: %w", err)
+	}
+
+	payloadDescriptor := content.NewDescriptorFromBytes(mediaTypeSignaturePayload, payload)
+
+	if err := repo.Push(ctx, payloadDescriptor, bytes.NewReader(payload)); err != nil {
+		return err
+	}
+
+	_, err = oras.PackManifest(
+		ctx,
+		repo,
+		oras.PackManifestVersion1_1_RC4,
+		artifactTypeSignature,
+		oras.PackManifestOptions{
+			Subject: &subject,
+			Layers:  []ocispec.Descriptor{payloadDescriptor},
+			ManifestAnnotations: map[string]string{
+				annotationSignature: base64.StdEncoding.EncodeToString(signature),
+			},
+		},
+	)
+
+	return err
+}
+
+// verifySignatures resolves a generator reference, and checks that one of its signatures is valid for one of the keys.
+// The resolved manifest is returned, it must be pulled by digest as tags can move.
+func verifySignatures(ctx context.Context, repo *remote.Repository, reference string, keys []crypto.PublicKey) (ocispec.Descriptor, error) {
+	subject, err := repo.Resolve(ctx, reference)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("could not resolve generator: %w", err)
+	}
+
+	var signatures []ocispec.Descriptor
+
+	if err := repo.Referrers(ctx, subject, artifactTypeSignature, func(referrers []ocispec.Descriptor) error {
+		signatures = append(signatures, referrers...)
+		return nil
+	}); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("could not list generator signatures: %w", err)
+	}
+
+	if len(signatures) == 0 {
+		return ocispec.Descriptor{}, fmt.Errorf("%w: generator %s is not signed", ErrUnverifiedGenerator, subject.Digest)
+	}
+
+	var errs []error
+
+	for _, signature := range signatures {
+		err := verifySignature(ctx, repo, subject, signature, keys)
+		if err == nil {
+			return subject, nil
+		}
+
+		errs = append(errs, fmt.Errorf("signature %s: %w", signature.Digest, err))
+	}
+
+	return ocispec.Descriptor{}, fmt.Errorf("%w: %w", ErrUnverifiedGenerator, errors.Join(errs...))
+}
+
+func verifySignature(ctx context.Context, fetcher content.Fetcher, subject, signatureDescriptor ocispec.Descriptor, keys []crypto.PublicKey) error {
+	manifestBytes, err := content.FetchAll(ctx, fetcher, signatureDescriptor)
+	if err != nil {
+		return err
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return err
+	}
+
+	if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != mediaTypeSignaturePayload {
+		return errors.New("malformed signature")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(manifest.Annotations[annotationSignature])
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+
+	payloadBytes, err := content.FetchAll(ctx, fetcher, manifest.Layers[0])
+	if err != nil {
+		return err
+	}
+
+	if !verify(keys, payloadBytes, signature) {
+		return errors.New("signature does not match any of the verification keys")
+	}
+
+	var payload signaturePayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return err
+	}
+
+	// Otherwise, the signature of another generator could be attached to this one.
+	if payload.Generator.Digest != subject.Digest || payload.Generator.Size != subject.Size {
+		return fmt.Errorf("signature is for generator %s", payload.Generator.Digest)
+	}
+
+	return nil
+}
+
+func sign(key crypto.Signer, payload []byte) ([]byte, error) {
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		return key.Sign(rand.Reader, payload, crypto.Hash(0))
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+		hash := sha256.Sum256(payload)
+		return key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key.Public())
+	}
+}
+
+func verify(keys []crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+
+	for _, key := range keys {
+		var ok bool
+
+		switch key := key.(type) {
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(key, payload, signature)
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(key, hash[:], signature)
+		case *rsa.PublicKey:
+			ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,233 @@
+package generator_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+func TestStore_RegistrySignatures(t *testing.T) {
+	var (
+		ctx = context.Background()
+		gen = generator.Generator{
+			Bin: []byte("coucou"),
+		}
+	)
+
+	ts := testutil.RunContainer(t, testutil.RegistryContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, ts.Shutdown(context.Background()))
+	})
+
+	signingKey, verificationKey := writeKeyPair(t)
+	_, otherVerificationKey := writeKeyPair(t)
+
+	signer, err := generator.LoadSigningKey(signingKey)
+	require.NoError(t, err)
+
+	publicKeys, err := generator.LoadVerificationKeys(verificationKey)
+	require.NoError(t, err)
+
+	otherPublicKeys, err := generator.LoadVerificationKeys(otherVerificationKey)
+	require.NoError(t, err)
+
+	signingStore, err := generator.DefaultStore(generator.WithSigningKey(signer))
+	require.NoError(t, err)
+
+	unsignedStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	verifyingStore, err := generator.DefaultStore(generator.WithVerificationKeys(publicKeys...))
+	require.NoError(t, err)
+
+	otherVerifyingStore, err := generator.DefaultStore(generator.WithVerificationKeys(otherPublicKeys...))
+	require.NoError(t, err)
+
+	signedURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/signed:v0.0.1")
+	require.NoError(t, err)
+
+	unsignedURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/unsigned:v0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, signingStore.Store(ctx, signedURL, &gen))
+	require.NoError(t, unsignedStore.Store(ctx, unsignedURL, &gen))
+
+	gotGen, err := verifyingStore.Load(ctx, signedURL)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+
+	_, err = verifyingStore.Load(ctx, unsignedURL)
+	assert.ErrorIs(t, err, generator.ErrUnverifiedGenerator)
+
+	_, err = otherVerifyingStore.Load(ctx, signedURL)
+	assert.ErrorIs(t, err, generator.ErrUnverifiedGenerator)
+
+	// Generators pushed without a signature can be signed afterwards.
+	require.NoError(t, generator.Sign(ctx, unsignedStore, unsignedURL, signer))
+
+	gotGen, err = verifyingStore.Load(ctx, unsignedURL)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+
+	fileURL, err := url.Parse("file://" + filepath.Join(t.TempDir(), "generator.wasm"))
+	require.NoError(t, err)
+
+	require.NoError(t, unsignedStore.Store(ctx, fileURL, &gen))
+
+	_, err = verifyingStore.Load(ctx, fileURL)
+	assert.ErrorIs(t, err, generator.ErrUnverifiedGenerator)
+
+	assert.Error(t, generator.Sign(ctx, unsignedStore, fileURL, signer))
+}
+
+// Signatures can be made without the CLI, following the format described in the README.
+func TestStore_RegistryExternalSignatures(t *testing.T) {
+	var (
+		ctx = context.Background()
+		gen = generator.Generator{
+			Bin: []byte("coucou"),
+		}
+	)
+
+	ts := testutil.RunContainer(t, testutil.RegistryContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, ts.Shutdown(context.Background()))
+	})
+
+	signingKey, verificationKey := writeKeyPair(t)
+
+	signer, err := generator.LoadSigningKey(signingKey)
+	require.NoError(t, err)
+
+	publicKeys, err := generator.LoadVerificationKeys(verificationKey)
+	require.NoError(t, err)
+
+	unsignedStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	verifyingStore, err := generator.DefaultStore(generator.WithVerificationKeys(publicKeys...))
+	require.NoError(t, err)
+
+	genURL, err := url.Parse("registry://localhost:" + ts.Port + "/testgenerators/external:v0.0.1")
+	require.NoError(t, err)
+
+	require.NoError(t, unsignedStore.Store(ctx, genURL, &gen))
+
+	repo, err := remote.NewRepository("localhost:" + ts.Port + "/testgenerators/external")
+	require.NoError(t, err)
+
+	repo.PlainHTTP = true
+
+	subject, err := repo.Resolve(ctx, "v0.0.1")
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(map[string]any{
+		"generator": map[string]any{
+			"mediaType": subject.MediaType,
+			"digest":    subject.Digest,
+			"size":      subject.Size,
+		},
+	})
+	require.NoError(t, err)
+
+	// Equivalent to openssl dgst -sha256 -sign key.pem payload.json.
+	hash := sha256.Sum256(payload)
+	signature, err := signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	payloadDescriptor := content.NewDescriptorFromBytes("application/vnd.dawg.generator.signature.payload.v1+json", payload)
+	require.NoError(t, repo.Push(ctx, payloadDescriptor, bytes.NewReader(payload)))
+
+	_, err = oras.PackManifest(
+		ctx,
+		repo,
+		oras.PackManifestVersion1_1_RC4,
+		"application/vnd.dawg.generator.signature.v1",
+		oras.PackManifestOptions{
+			Subject: &subject,
+			Layers:  []ocispec.Descriptor{payloadDescriptor},
+			ManifestAnnotations: map[string]string{
+				"cc.urcloud.dawg.signature": base64.StdEncoding.EncodeToString(signature),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	gotGen, err := verifyingStore.Load(ctx, genURL)
+	require.NoError(t, err)
+	assertPulledGenerator(t, &gen, gotGen)
+}
+
+func TestLoadVerificationKeys(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundlePath := filepath.Join(t.TempDir(), "keys.pem")
+	require.NoError(t, os.WriteFile(bundlePath, append(encodePublicKey(t, ecdsaKey.Public()), encodePublicKey(t, ed25519Key)...), 0600))
+
+	keys, err := generator.LoadVerificationKeys(bundlePath)
+	require.NoError(t, err)
+	assert.Equal(t, []crypto.PublicKey{ecdsaKey.Public(), ed25519Key}, keys)
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0600))
+
+	_, err = generator.LoadVerificationKeys(emptyPath)
+	assert.Error(t, err)
+}
+
+// writeKeyPair generates an ECDSA key pair, and returns the paths of the PEM private and public keys.
+func writeKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	privateBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	var (
+		workDir    = t.TempDir()
+		privateKey = filepath.Join(workDir, "key.pem")
+		publicKey  = filepath.Join(workDir, "key.pub")
+	)
+
+	require.NoError(t, os.WriteFile(privateKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes}), 0600))
+	require.NoError(t, os.WriteFile(publicKey, encodePublicKey(t, key.Public()), 0600))
+
+	return privateKey, publicKey
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	t.Helper()
+
+	publicBytes, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"net/url"
 )
@@ -22,6 +23,11 @@ type Store interface {
 	Writer
 }
 
+// URLChecker tells if a store is able to handle an URL, without reaching out to the generator.
+type URLChecker interface {
+	Supports(*url.URL) bool
+}
+
 type unsupportedSchemeError string
 
 func (e unsupportedSchemeError) Error() string {
@@ -31,6 +37,11 @@ func (e unsupportedSchemeError) Error() string {
 // schemeStore provides using a specific Provider based on  the URL scheme.
 type schemeStore map[string]Store
 
+func (s schemeStore) Supports(url *url.URL) bool {
+	_, ok := s[url.Scheme]
+	return ok
+}
+
 func (s schemeStore) Load(ctx context.Context, url *url.URL) (*Generator, error) {
 	st, ok := s[url.Scheme]
 	if !ok {
@@ -40,6 +51,24 @@ func (s schemeStore) Load(ctx context.Context, url *url.URL) (*Generator, error)
 	return st.Load(ctx, url)
 }
 
+func (s schemeStore) Inspect(ctx context.Context, url *url.URL) (*Manifest, error) {
+	st, ok := s[url.Scheme]
+	if !ok {
+		return nil, unsupportedSchemeError(url.Scheme)
+	}
+
+	return Inspect(ctx, st, url)
+}
+
+func (s schemeStore) Sign(ctx context.Context, url *url.URL, key crypto.Signer) error {
+	st, ok := s[url.Scheme]
+	if !ok {
+		return unsupportedSchemeError(url.Scheme)
+	}
+
+	return Sign(ctx, st, url, key)
+}
+
 func (s schemeStore) Store(ctx context.Context, url *url.URL, g *Generator) error {
 	st, ok := s[url.Scheme]
 	if !ok {
@@ -49,10 +78,87 @@ func (s schemeStore) Store(ctx context.Context, url *url.URL, g *Generator) erro
 	return st.Store(ctx, url, g)
 }
 
-func DefaultStore() (Store, error) {
-	registryStore := newRegistryStore()
+// StoreOpt configures the default store.
+type StoreOpt func(*storeOptions)
+
+type storeOptions struct {
+	registryCredentials CredentialFunc
+	registriesConfig    *RegistriesConfig
+	cacheDir            string
+	cacheMaxSize        int64
+	signingKey          crypto.Signer
+	verificationKeys    []crypto.PublicKey
+}
+
+// WithRegistryCredentials configures the credentials used to pull and push generators from and to OCI registries.
+func WithRegistryCredentials(credentials CredentialFunc) StoreOpt {
+	return func(opts *storeOptions) {
+		opts.registryCredentials = credentials
+	}
+}
+
+// WithRegistriesConfig configures how to reach OCI registries, DefaultRegistriesConfig is used otherwise.
+func WithRegistriesConfig(cfg *RegistriesConfig) StoreOpt {
+	return func(opts *storeOptions) {
+		opts.registriesConfig = cfg
+	}
+}
+
+// WithRegistryCacheDir persists the generators pulled from registries in the given directory,
+// instead of keeping them in memory. The least recently used ones are deleted when the cache grows over maxSize bytes.
+func WithRegistryCacheDir(dir string, maxSize int64) StoreOpt {
+	return func(opts *storeOptions) {
+		opts.cacheDir = dir
+		opts.cacheMaxSize = maxSize
+	}
+}
+
+// WithSigningKey signs the generators pushed to registries.
+func WithSigningKey(key crypto.Signer) StoreOpt {
+	return func(opts *storeOptions) {
+		opts.signingKey = key
+	}
+}
+
+// WithVerificationKeys refuses to load generators that are not signed by one of the given keys.
+// Generators loaded from files are refused, as they can't be signed.
+func WithVerificationKeys(keys ...crypto.PublicKey) StoreOpt {
+	return func(opts *storeOptions) {
+		opts.verificationKeys = keys
+	}
+}
+
+func DefaultStore(opts ...StoreOpt) (Store, error) {
+	options := storeOptions{
+		registriesConfig: DefaultRegistriesConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var cache *blobCache
+
+	if options.cacheDir != "" {
+		var err error
+
+		cache, err = newBlobCache(options.cacheDir, options.cacheMaxSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registryStore, err := newRegistryStore(options.registriesConfig, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	registryStore.credentials = options.registryCredentials
+	registryStore.signingKey = options.signingKey
+	registryStore.verificationKeys = options.verificationKeys
+
 	return &schemeStore{
-		fileScheme:     &fileStore{},
+		fileScheme:     &fileStore{requireSignature: len(options.verificationKeys) > 0},
 		registryScheme: registryStore,
 	}, nil
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() uint64 {
+	genCtx, err := gdk.ReadContext()
+	if err != nil {
+		return gdk.Error(err)
+	}
+
+	// The context is read-only, generators cannot tamper with it.
+	if err := os.WriteFile(gdk.ContextPath, []byte("{}"), 0o600); err == nil {
+		return gdk.Error(os.ErrPermission)
+	}
+
+	out, err := json.Marshal(genCtx)
+	if err != nil {
+		return gdk.Error(err)
+	}
+
+	return gdk.WriteOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() uint64 {
+	out, err := gdk.MarshalEnvelope(
+		gdk.EnvelopeResource{Kind: gdk.ResourceKindDashboard, Payload: []byte(`{"title":"dashboard"}`)},
+		gdk.EnvelopeResource{Kind: gdk.ResourceKindAlertRule, Payload: []byte(`{"uid":"rule"}`)},
+	)
+	if err != nil {
+		return gdk.Error(err)
+	}
+
+	return gdk.WriteOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() {
+	configBytes, err := os.ReadFile(gdk.InputPath)
+	if err != nil {
+		gdk.SetError(err)
+		return
+	}
+
+	gdk.Log(gdk.LogLevelInfo, "generating")
+	gdk.AddResource("alert-rule", []byte(`{"title":"rule"}`))
+	gdk.SetOutput(configBytes)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
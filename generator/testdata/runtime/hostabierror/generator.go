@@ -0,0 +1,17 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() {
+	gdk.Log(gdk.LogLevelError, "about to fail")
+	gdk.SetError(errors.New("something went wrong"))
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
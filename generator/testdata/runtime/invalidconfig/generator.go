@@ -0,0 +1,16 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() uint64 {
+	return gdk.Error(gdk.InvalidConfigError("app_name", errors.New("must not be empty")))
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() uint64 {
+	fmt.Fprintln(os.Stdout, "hello from stdout")
+	fmt.Fprintln(os.Stderr, "hello from stderr")
+
+	return gdk.WriteOutput([]byte(`{}`))
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,14 @@
+package main
+
+var chunks [][]byte
+
+//export generate
+func generate() uint64 {
+	for {
+		chunks = append(chunks, make([]byte, 1<<20))
+	}
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
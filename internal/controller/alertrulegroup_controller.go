@@ -0,0 +1,417 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const alertRuleGroupFinalizer = "alertrulegroup.dawg.urcloud.cc/finalizer"
+
+var errInvalidRuleGroup = errors.New("rule group must have a title and a folderUid")
+
+// AlertRuleGroupReconciler reconciles an AlertRuleGroup object.
+// A rule group is identified in Grafana by its folder and title: when several groups generate the same folder and title
+// on the same server and organization, the oldest one is applied and the others are rejected.
+type AlertRuleGroupReconciler struct {
+	options
+	statusReporter
+
+	// apiReader reads secrets straight from the API server, to avoid caching all the secrets of the cluster.
+	apiReader      client.Reader
+	generatorStore generator.Reader
+	runtime        generator.Runtime
+	// grafana is the Grafana of the controller, nil if rule groups must reference a GrafanaInstance.
+	grafana   *grafana.Client
+	instances *grafanaPool
+}
+
+func NewAlertRuleGroupReconciler(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client, opts ...Option) *AlertRuleGroupReconciler {
+	options := newOptions(opts)
+
+	return &AlertRuleGroupReconciler{
+		options:        options,
+		generatorStore: store,
+		runtime:        runtime,
+		grafana:        grafana,
+		instances:      newGrafanaPool(options.grafanaClientOpts),
+	}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=alertrulegroups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=alertrulegroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=alertrulegroups/finalizers,verbs=update
+
+// Reconcile handles rule group reconciliation.
+func (r *AlertRuleGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var group dawgv1.AlertRuleGroup
+
+	if err := r.k8sClient.Get(ctx, req.NamespacedName, &group); err != nil {
+		logger.Error(err, "Could not fetch the rule group")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !group.DeletionTimestamp.IsZero() {
+		return r.deleteRuleGroup(ctx, &group, logger)
+	}
+
+	return r.applyRuleGroup(ctx, &group, logger)
+}
+
+func (r *AlertRuleGroupReconciler) applyRuleGroup(ctx context.Context, group *dawgv1.AlertRuleGroup, logger logr.Logger) (ctrl.Result, error) {
+	logger = logger.WithValues("generator", group.Spec.Generator, "grafana_instance", group.Spec.GrafanaInstance)
+
+	logger.Info("Applying rule group")
+
+	if !controllerutil.ContainsFinalizer(group, alertRuleGroupFinalizer) {
+		controllerutil.AddFinalizer(group, alertRuleGroupFinalizer)
+		if err := r.k8sClient.Update(ctx, group); err != nil {
+			r.setFailureStatus(ctx, group, reasonFinalizerFailed, "Could set finalizer", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	genResult, digest, runErr := r.runGenerator(
+		ctx,
+		r.apiReader,
+		r.generatorStore,
+		r.runtime,
+		generatorRun{
+			resource:    resourceContext("AlertRuleGroup", group),
+			generator:   group.Spec.Generator,
+			config:      group.Spec.Config,
+			configFrom:  group.Spec.ConfigFrom,
+			pullSecrets: group.Spec.ImagePullSecrets,
+		},
+		logger,
+	)
+	if digest != "" {
+		group.Status.GeneratorDigest = digest
+	}
+
+	if runErr != nil {
+		r.setFailureStatus(ctx, group, runErr.reason, runErr.message, runErr.err, logger)
+
+		if !runErr.retry {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, runErr.err
+	}
+
+	payload, ruleGroup, err := ruleGroupOutput(genResult)
+	if err != nil {
+		r.setFailureStatus(ctx, group, reasonInvalidOutput, "Generator output is invalid", err, logger)
+		// The output won't change until the generator or its config does.
+		return ctrl.Result{}, nil
+	}
+
+	logger = logger.WithValues("folder_uid", ruleGroup.FolderUID, "title", ruleGroup.Title)
+
+	var groups dawgv1.AlertRuleGroupList
+	if err := r.k8sClient.List(ctx, &groups); err != nil {
+		r.setFailureStatus(ctx, group, reasonConflict, "Could not list the rule groups", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	target := r.ruleGroupTarget(ctx, group.Namespace, group.Spec.GrafanaInstance, ruleGroup.FolderUID, ruleGroup.Title)
+
+	// The rule group is identified by its folder and title, if one of them or the instance changes the previous group must go.
+	// It is only deleted if this rule group owned it, another one may have applied it.
+	if previous := group.Status; previous.Title != "" {
+		previousTarget := r.ruleGroupTarget(ctx, group.Namespace, previous.GrafanaInstance, previous.FolderUID, previous.Title)
+
+		if previousTarget != target && r.ownsRuleGroup(ctx, groups.Items, group, previousTarget) {
+			if err := r.deleteGrafanaRuleGroup(ctx, group); err != nil {
+				r.setFailureStatus(ctx, group, reasonGrafanaFailed, "Could not delete the previous rule group from Grafana", err, logger)
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// The status records the group claimed by the rule group, even when it is rejected, for the conflicts to be detected.
+	group.Status.FolderUID = ruleGroup.FolderUID
+	group.Status.Title = ruleGroup.Title
+	group.Status.GrafanaInstance = group.Spec.GrafanaInstance
+
+	if !r.ownsRuleGroup(ctx, groups.Items, group, target) {
+		group.Status.Rules = 0
+
+		r.setFailureStatus(
+			ctx,
+			group,
+			reasonConflict,
+			"Another rule group has the same folder and title on the same Grafana server and organization",
+			fmt.Errorf("rule group %s/%s is owned by an older AlertRuleGroup", ruleGroup.FolderUID, ruleGroup.Title),
+			logger,
+		)
+
+		// The rule group is reconciled again once the owner is deleted or changes.
+		return ctrl.Result{}, nil
+	}
+
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: group.Namespace, Name: group.Spec.GrafanaInstance},
+	)
+	if err != nil {
+		r.setFailureStatus(ctx, group, reasonInstancesFailed, "Could not resolve the Grafana instance of the rule group", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	if _, err := cl.PutAlertRuleGroup(
+		ctx,
+		&grafana.PutAlertRuleGroupRequest{
+			FolderUID: ruleGroup.FolderUID,
+			Title:     ruleGroup.Title,
+			Group:     payload,
+		},
+	); err != nil {
+		r.setFailureStatus(ctx, group, reasonGrafanaFailed, "Could not create or update the rule group in Grafana", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	group.Status.Rules = len(ruleGroup.Rules)
+
+	r.setStatus(ctx, group, metav1.ConditionTrue, reasonReady, "Rule group is ready", logger)
+
+	logger.Info("Applied rule group", "rules", len(ruleGroup.Rules))
+
+	return ctrl.Result{}, nil
+}
+
+// ruleGroupTarget identifies a rule group of a Grafana server and organization.
+// If the instance cannot be resolved, the rule group only competes with the rule groups referencing the same instance.
+func (r *AlertRuleGroupReconciler) ruleGroupTarget(ctx context.Context, namespace, instance, folderUID, title string) string {
+	server, err := grafanaServer(ctx, r.k8sClient, r.grafana, types.NamespacedName{Namespace: namespace, Name: instance})
+	if err != nil {
+		server = "instance:" + namespace + "/" + instance
+	}
+
+	return server + "/" + folderUID + "/" + title
+}
+
+// ownsRuleGroup tells if a rule group is applied to a target: if it is older than all the other ones claiming it,
+// deleted rule groups included until they are gone.
+func (r *AlertRuleGroupReconciler) ownsRuleGroup(ctx context.Context, groups []dawgv1.AlertRuleGroup, group *dawgv1.AlertRuleGroup, target string) bool {
+	for i := range groups {
+		other := &groups[i]
+
+		if client.ObjectKeyFromObject(other) == client.ObjectKeyFromObject(group) || other.Status.Title == "" {
+			continue
+		}
+
+		if r.ruleGroupTarget(ctx, other.Namespace, other.Status.GrafanaInstance, other.Status.FolderUID, other.Status.Title) != target {
+			continue
+		}
+
+		if olderRuleGroup(other, group) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func olderRuleGroup(a, b *dawgv1.AlertRuleGroup) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+// ruleGroupOutput extracts the rule group produced by a generator, either as its main payload or as the only resource of its envelope.
+func ruleGroupOutput(result *generator.ExecutionResult) (json.RawMessage, *grafana.AlertRuleGroup, error) {
+	payload, err := singleResourceOutput(result, gdk.ResourceKindAlertRuleGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ruleGroup grafana.AlertRuleGroup
+	if err := json.Unmarshal(payload, &ruleGroup); err != nil {
+		return nil, nil, fmt.Errorf("could not decode rule group: %w", err)
+	}
+
+	if ruleGroup.Title == "" || ruleGroup.FolderUID == "" {
+		return nil, nil, errInvalidRuleGroup
+	}
+
+	return payload, &ruleGroup, nil
+}
+
+func (r *AlertRuleGroupReconciler) deleteRuleGroup(ctx context.Context, group *dawgv1.AlertRuleGroup, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(group, alertRuleGroupFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	var groups dawgv1.AlertRuleGroupList
+	if err := r.k8sClient.List(ctx, &groups); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch {
+	case group.Status.Title == "":
+		logger.Info("Deleting a rule group never applied, not deleting the Grafana rule group")
+	case !r.ownsRuleGroup(ctx, groups.Items, group, r.ruleGroupTarget(
+		ctx,
+		group.Namespace,
+		group.Status.GrafanaInstance,
+		group.Status.FolderUID,
+		group.Status.Title,
+	)):
+		logger.Info("Deleting a rule group owned by another AlertRuleGroup, not deleting the Grafana rule group")
+	default:
+		logger.Info("Deleting rule group")
+
+		if err := r.deleteGrafanaRuleGroup(ctx, group); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(group, alertRuleGroupFinalizer)
+	if err := r.k8sClient.Update(ctx, group); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted rule group")
+
+	return ctrl.Result{}, nil
+}
+
+// deleteGrafanaRuleGroup deletes the rule group recorded in the status from the instance it was applied to.
+// There is nothing to delete if the GrafanaInstance is gone.
+func (r *AlertRuleGroupReconciler) deleteGrafanaRuleGroup(ctx context.Context, group *dawgv1.AlertRuleGroup) error {
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: group.Namespace, Name: group.Status.GrafanaInstance},
+	)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	err = cl.DeleteAlertRuleGroup(
+		ctx,
+		&grafana.DeleteAlertRuleGroupRequest{FolderUID: group.Status.FolderUID, Title: group.Status.Title},
+	)
+	if err != nil && !grafana.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// competingRuleGroups enqueues the rule groups rejected because of a conflict, and the ones claiming the group a rule group claims.
+func (r *AlertRuleGroupReconciler) competingRuleGroups(ctx context.Context, obj client.Object) []reconcile.Request {
+	changed, ok := obj.(*dawgv1.AlertRuleGroup)
+	if !ok {
+		return nil
+	}
+
+	var groups dawgv1.AlertRuleGroupList
+
+	if err := r.k8sClient.List(ctx, &groups); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the rule groups")
+		return nil
+	}
+
+	target := r.ruleGroupTarget(ctx, changed.Namespace, changed.Status.GrafanaInstance, changed.Status.FolderUID, changed.Status.Title)
+
+	var requests []reconcile.Request
+
+	for _, group := range groups.Items {
+		if client.ObjectKeyFromObject(&group) == client.ObjectKeyFromObject(changed) {
+			continue
+		}
+
+		cond := meta.FindStatusCondition(group.Status.Conditions, dawgv1.AlertRuleGroupConditionReady)
+		conflicting := cond != nil && cond.Reason == reasonConflict
+
+		if conflicting || (changed.Status.Title != "" && r.ruleGroupTarget(
+			ctx,
+			group.Namespace,
+			group.Status.GrafanaInstance,
+			group.Status.FolderUID,
+			group.Status.Title,
+		) == target) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&group)})
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AlertRuleGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.k8sClient = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(
+			&dawgv1.AlertRuleGroup{},
+			// Do not process status updates, nor delete events as we're using finalizers.
+			builder.WithPredicates(
+				predicate.GenerationChangedPredicate{},
+				predicate.Funcs{
+					DeleteFunc: func(e event.DeleteEvent) bool { return false },
+				},
+			),
+		).
+		// Rule groups rejected because of a conflict are reconciled again when the group claimed by another rule group changes,
+		// which is only visible in its status, or when it goes away.
+		Watches(
+			&dawgv1.AlertRuleGroup{},
+			handler.EnqueueRequestsFromMapFunc(r.competingRuleGroups),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldGroup, okOld := e.ObjectOld.(*dawgv1.AlertRuleGroup)
+					newGroup, okNew := e.ObjectNew.(*dawgv1.AlertRuleGroup)
+					if !okOld || !okNew {
+						return false
+					}
+
+					return oldGroup.Generation != newGroup.Generation ||
+						oldGroup.Status.GrafanaInstance != newGroup.Status.GrafanaInstance ||
+						oldGroup.Status.FolderUID != newGroup.Status.FolderUID ||
+						oldGroup.Status.Title != newGroup.Status.Title
+				},
+			}),
+		).
+		Complete(r)
+}
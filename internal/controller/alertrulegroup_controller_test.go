@@ -0,0 +1,270 @@
+package controller_test
+
+import (
+	"context"
+	_ "embed"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//go:generate tinygo build -o ./testdata/rules.wasm -scheduler=none --no-debug -target wasi ./testdata/rules
+//go:embed testdata/rules.wasm
+var rulesBin []byte
+
+func TestAlertRuleGroupController_CreatesDeletesRuleGroup(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/v1/provisioning/folder/alerts-folder/rule-groups/rules": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"title":"rules","folderUid":"alerts-folder","interval":60,"rules":[{"uid":"rule-uid","title":"rule"}]}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewAlertRuleGroupReconciler(
+				fakeStore{"fake://foo/bar/rules:v1": {Bin: rulesBin}},
+				genRuntime,
+				grafanaClient,
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	group := dawgv1.AlertRuleGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-rules",
+			Namespace: "default",
+		},
+		Spec: dawgv1.AlertRuleGroupSpec{
+			Generator: "fake://foo/bar/rules:v1",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &group)
+	require.NoError(t, err)
+
+	// This should upsert the rule group.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	putRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPut, putRequest.Method)
+	assert.JSONEq(
+		t,
+		`{"title":"rules","folderUid":"alerts-folder","interval":60,"rules":[{"uid":"rule-uid","title":"rule"}]}`,
+		readAll(t, grafanaBackend.readRequestBody(t, 0)),
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      group.Name,
+				Namespace: group.Namespace,
+			},
+			&group,
+		)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(group.Status.Conditions, dawgv1.AlertRuleGroupConditionReady)
+	})
+
+	assert.Equal(t, "alerts-folder", group.Status.FolderUID)
+	assert.Equal(t, "rules", group.Status.Title)
+	assert.Equal(t, 1, group.Status.Rules)
+
+	err = k8sClient.Delete(ctx, &group)
+	require.NoError(t, err)
+
+	// This should delete the rule group.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/v1/provisioning/folder/alerts-folder/rule-groups/rules", deleteRequest.URL.Path)
+}
+
+func TestAlertRuleGroupController_RejectsConflictingRuleGroups(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/v1/provisioning/folder/alerts-folder/rule-groups/rules": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"title":"rules","folderUid":"alerts-folder","interval":60,"rules":[{"uid":"rule-uid","title":"rule"}]}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewAlertRuleGroupReconciler(
+				fakeStore{"fake://foo/bar/rules:v1": {Bin: rulesBin}},
+				genRuntime,
+				grafanaClient,
+				controller.WithGrafanaClientOptions(grafana.WithRoundTripper(&grafanaBackend)),
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	ownerGroup := dawgv1.AlertRuleGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-rules",
+			Namespace: "default",
+		},
+		Spec: dawgv1.AlertRuleGroupSpec{
+			Generator: "fake://foo/bar/rules:v1",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &ownerGroup)
+	require.NoError(t, err)
+
+	// This should upsert the rule group.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	putRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPut, putRequest.Method)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&ownerGroup), &ownerGroup)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(ownerGroup.Status.Conditions, dawgv1.AlertRuleGroupConditionReady)
+	})
+
+	// The same Grafana, reached through a GrafanaInstance.
+	err = k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-tokens", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	})
+	require.NoError(t, err)
+
+	err = k8sClient.Create(ctx, &dawgv1.GrafanaInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "same-grafana", Namespace: "default"},
+		Spec: dawgv1.GrafanaInstanceSpec{
+			URL: "http://somegrafana.com",
+			TokenSecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+				Key:                  "token",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	conflictingGroup := dawgv1.AlertRuleGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "b-rules",
+			Namespace: "default",
+		},
+		Spec: dawgv1.AlertRuleGroupSpec{
+			Generator:       "fake://foo/bar/rules:v1",
+			Config:          "some: config",
+			GrafanaInstance: "same-grafana",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &conflictingGroup)
+	require.NoError(t, err)
+
+	// The conflicting rule group is rejected without touching Grafana.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&conflictingGroup), &conflictingGroup)
+		require.NoError(t, err)
+
+		cond := meta.FindStatusCondition(conflictingGroup.Status.Conditions, dawgv1.AlertRuleGroupConditionReady)
+		return cond != nil && cond.Reason == "Conflict"
+	})
+
+	assert.Zero(t, conflictingGroup.Status.Rules)
+
+	err = k8sClient.Delete(ctx, &ownerGroup)
+	require.NoError(t, err)
+
+	// This should delete the rule group, then apply the one of the remaining rule group.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+
+	putRequest = grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodPut, putRequest.Method)
+	assert.Equal(t, "Bearer token", putRequest.Header.Get("Authorization"))
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&conflictingGroup), &conflictingGroup)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(conflictingGroup.Status.Conditions, dawgv1.AlertRuleGroupConditionReady)
+	})
+
+	assert.Equal(t, "same-grafana", conflictingGroup.Status.GrafanaInstance)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const contactPointFinalizer = "contactpoint.dawg.urcloud.cc/finalizer"
+
+var errInvalidContactPoint = errors.New("contact point must have a name and a type")
+
+// ContactPointReconciler reconciles a ContactPoint object
+type ContactPointReconciler struct {
+	uidResourceReconciler[*dawgv1.ContactPoint, *grafana.ContactPoint]
+}
+
+func NewContactPointReconciler(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client, opts ...Option) *ContactPointReconciler {
+	return &ContactPointReconciler{
+		uidResourceReconciler: newUIDResourceReconciler(contactPointHandler, store, runtime, grafana, opts),
+	}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=contactpoints,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=contactpoints/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=contactpoints/finalizers,verbs=update
+
+var contactPointHandler = uidResourceHandler[*dawgv1.ContactPoint, *grafana.ContactPoint]{
+	name:       "contact point",
+	kind:       "ContactPoint",
+	outputKind: gdk.ResourceKindContactPoint,
+	finalizer:  contactPointFinalizer,
+	newObject:  func() *dawgv1.ContactPoint { return &dawgv1.ContactPoint{} },
+	spec: func(contactPoint *dawgv1.ContactPoint) (dawgv1.PayloadSource, string) {
+		return contactPoint.Spec.PayloadSource, contactPoint.Spec.GrafanaInstance
+	},
+	status: func(contactPoint *dawgv1.ContactPoint) uidResourceStatus {
+		return uidResourceStatus{
+			uid:             &contactPoint.Status.UID,
+			grafanaInstance: &contactPoint.Status.GrafanaInstance,
+			generatorDigest: &contactPoint.Status.GeneratorDigest,
+		}
+	},
+	decode: contactPointPayload,
+	apply:  applyContactPoint,
+	delete: func(ctx context.Context, cl *grafana.Client, uid string) error {
+		return cl.DeleteContactPoint(ctx, &grafana.DeleteContactPointRequest{UID: uid})
+	},
+	applied: func(contactPoint *dawgv1.ContactPoint, desired *grafana.ContactPoint) {
+		contactPoint.Status.Name = desired.Name
+		contactPoint.Status.Type = desired.Type
+	},
+}
+
+// contactPointPayload validates a contact point payload, and sets its UID if it has none.
+func contactPointPayload(payload json.RawMessage, uid string) (json.RawMessage, *grafana.ContactPoint, string, error) {
+	payload, err := withUID(payload, uid)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("could not decode contact point: %w", err)
+	}
+
+	var contactPoint grafana.ContactPoint
+	if err := json.Unmarshal(payload, &contactPoint); err != nil {
+		return nil, nil, "", fmt.Errorf("could not decode contact point: %w", err)
+	}
+
+	if contactPoint.Name == "" || contactPoint.Type == "" {
+		return nil, nil, "", errInvalidContactPoint
+	}
+
+	return payload, &contactPoint, contactPoint.UID, nil
+}
+
+func applyContactPoint(ctx context.Context, cl *grafana.Client, uid string, payload json.RawMessage) error {
+	err := cl.UpdateContactPoint(ctx, &grafana.UpdateContactPointRequest{UID: uid, ContactPoint: payload})
+	if grafana.IsNotFound(err) {
+		_, err = cl.CreateContactPoint(ctx, &grafana.CreateContactPointRequest{ContactPoint: payload})
+	}
+
+	return err
+}
@@ -0,0 +1,126 @@
+package controller_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestContactPointController_CreatesDeletesInlineContactPoint(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				// The contact point does not exist yet, the update is answered with a 404.
+				"http://somegrafana.com/api/v1/provisioning/contact-points/oncall": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusNotFound,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"contact point not found"}`)),
+					}
+				},
+				"http://somegrafana.com/api/v1/provisioning/contact-points": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusAccepted,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"uid":"oncall","name":"On call","type":"email","settings":{"addresses":"oncall@example.com"}}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewContactPointReconciler(fakeStore{}, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	contactPoint := dawgv1.ContactPoint{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oncall",
+			Namespace: "default",
+		},
+		Spec: dawgv1.ContactPointSpec{
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{
+					Raw: []byte(`{"uid":"oncall","name":"On call","type":"email","settings":{"addresses":"oncall@example.com"}}`),
+				},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &contactPoint)
+	require.NoError(t, err)
+
+	// This should try to update the contact point, then create it.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	updateRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPut, updateRequest.Method)
+
+	createRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodPost, createRequest.Method)
+	assert.JSONEq(
+		t,
+		`{"uid":"oncall","name":"On call","type":"email","settings":{"addresses":"oncall@example.com"}}`,
+		readAll(t, grafanaBackend.readRequestBody(t, 1)),
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&contactPoint), &contactPoint)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(contactPoint.Status.Conditions, dawgv1.AlertingConditionReady)
+	})
+
+	assert.Equal(t, "oncall", contactPoint.Status.UID)
+	assert.Equal(t, "On call", contactPoint.Status.Name)
+	assert.Equal(t, "email", contactPoint.Status.Type)
+
+	err = k8sClient.Delete(ctx, &contactPoint)
+	require.NoError(t, err)
+
+	// This should delete the contact point.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/v1/provisioning/contact-points/oncall", deleteRequest.URL.Path)
+}
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+)
+
+var errInvalidConfigSource = errors.New("config source must reference a ConfigMap or a Secret key")
+
+// generatorConfig returns the config a generator runs with: the config sources merged in order, then the inline config.
+func generatorConfig(ctx context.Context, reader client.Reader, namespace, inline string, sources []dawgv1.ConfigSource) ([]byte, error) {
+	if len(sources) == 0 {
+		return []byte(inline), nil
+	}
+
+	var merged map[string]any
+
+	for i, source := range sources {
+		data, ok, err := readConfigSource(ctx, reader, namespace, source)
+		if err != nil {
+			return nil, fmt.Errorf("configFrom[%d]: %w", i, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		merged, err = mergeConfig(merged, data)
+		if err != nil {
+			return nil, fmt.Errorf("configFrom[%d]: %w", i, err)
+		}
+	}
+
+	merged, err := mergeConfig(merged, []byte(inline))
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if merged == nil {
+		return nil, nil
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// readConfigSource returns the config held by a ConfigMap or a Secret key.
+// It returns false if an optional source does not exist.
+func readConfigSource(ctx context.Context, reader client.Reader, namespace string, source dawgv1.ConfigSource) ([]byte, bool, error) {
+	switch {
+	case source.ConfigMapKeyRef != nil:
+		var (
+			ref       = source.ConfigMapKeyRef
+			configMap corev1.ConfigMap
+		)
+
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &configMap); err != nil {
+			if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+				return nil, false, nil
+			}
+
+			return nil, false, fmt.Errorf("could not get ConfigMap %q: %w", ref.Name, err)
+		}
+
+		if data, ok := configMap.Data[ref.Key]; ok {
+			return []byte(data), true, nil
+		}
+
+		if data, ok := configMap.BinaryData[ref.Key]; ok {
+			return data, true, nil
+		}
+
+		if isOptional(ref.Optional) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("ConfigMap %q has no key %q", ref.Name, ref.Key)
+	case source.SecretKeyRef != nil:
+		var (
+			ref    = source.SecretKeyRef
+			secret corev1.Secret
+		)
+
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+			if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+				return nil, false, nil
+			}
+
+			return nil, false, fmt.Errorf("could not get Secret %q: %w", ref.Name, err)
+		}
+
+		if data, ok := secret.Data[ref.Key]; ok {
+			return data, true, nil
+		}
+
+		if isOptional(ref.Optional) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("Secret %q has no key %q", ref.Name, ref.Key)
+	default:
+		return nil, false, errInvalidConfigSource
+	}
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+// mergeConfig merges a YAML config into base. Objects are merged key by key, other values are replaced.
+func mergeConfig(base map[string]any, data []byte) (map[string]any, error) {
+	var config map[string]any
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("must be a YAML object: %w", err)
+	}
+
+	if config == nil {
+		return base, nil
+	}
+
+	if base == nil {
+		return config, nil
+	}
+
+	return mergeValues(base, config), nil
+}
+
+func mergeValues(base, override map[string]any) map[string]any {
+	for key, value := range override {
+		baseMap, baseIsMap := base[key].(map[string]any)
+		overrideMap, overrideIsMap := value.(map[string]any)
+
+		if baseIsMap && overrideIsMap {
+			base[key] = mergeValues(baseMap, overrideMap)
+			continue
+		}
+
+		base[key] = value
+	}
+
+	return base
+}
+
+// configSourceDashboards enqueues the dashboards reading their config from a ConfigMap, or from a Secret.
+func (r *DashboardReconciler) configSourceDashboards(secret bool) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var dashboards dawgv1.DashboardList
+
+		if err := r.k8sClient.List(ctx, &dashboards, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "Could not list the dashboards reading their config from an object")
+			return nil
+		}
+
+		var requests []reconcile.Request
+
+		for _, dashboard := range dashboards.Items {
+			if readsConfigFrom(&dashboard, obj.GetName(), secret) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+			}
+		}
+
+		return requests
+	}
+}
+
+func readsConfigFrom(dashboard *dawgv1.Dashboard, name string, secret bool) bool {
+	for _, source := range dashboard.Spec.ConfigFrom {
+		switch {
+		case secret && source.SecretKeyRef != nil && source.SecretKeyRef.Name == name:
+			return true
+		case !secret && source.ConfigMapKeyRef != nil && source.ConfigMapKeyRef.Name == name:
+			return true
+		}
+	}
+
+	return false
+}
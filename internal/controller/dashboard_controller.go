@@ -2,17 +2,29 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/url"
-
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/go-logr/logr"
 	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
 	"github.com/jlevesy/dawg/generator"
 	"github.com/jlevesy/dawg/pkg/grafana"
 )
@@ -21,23 +33,40 @@ const finalizer = "dashboard.dawg.urcloud.cc/finalizer"
 
 // DashboardReconciler reconciles a Dashboard object
 type DashboardReconciler struct {
-	k8sClient      client.Client
+	options
+
+	k8sClient client.Client
+	// apiReader reads secrets straight from the API server, to avoid caching all the secrets of the cluster.
+	apiReader      client.Reader
 	generatorStore generator.Reader
 	runtime        generator.Runtime
-	grafana        *grafana.Client
+	// grafana is the Grafana of the controller, dashboards must reference GrafanaInstances if nil.
+	grafana   *grafana.Client
+	instances *grafanaPool
+	recorder  record.EventRecorder
 }
 
-func NewDashboardReconciller(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client) *DashboardReconciler {
+func NewDashboardReconciller(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client, opts ...Option) *DashboardReconciler {
+	options := newOptions(opts)
+
 	return &DashboardReconciler{
+		options:        options,
 		generatorStore: store,
 		runtime:        runtime,
 		grafana:        grafana,
+		instances:      newGrafanaPool(options.grafanaClientOpts),
 	}
 }
 
 //+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=dashboards,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=dashboards/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=dashboards/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanafolders,verbs=get;list;watch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanainstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=librarypanels,verbs=get;list;watch
 
 // Reconcile handles dashboard reconciliation.
 func (r *DashboardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -68,6 +97,8 @@ func (r *DashboardReconciler) applyDashboard(ctx context.Context, dashboard *daw
 			r.setFailureStatus(
 				ctx,
 				dashboard,
+				dawgv1.DashboardConditionReady,
+				reasonFinalizerFailed,
 				"Could set finalizer",
 				err,
 				logger,
@@ -81,6 +112,8 @@ func (r *DashboardReconciler) applyDashboard(ctx context.Context, dashboard *daw
 		r.setFailureStatus(
 			ctx,
 			dashboard,
+			dawgv1.DashboardConditionGeneratorFetched,
+			reasonInvalidReference,
 			"Could not parse generator refererence as an URL",
 			err,
 			logger,
@@ -89,78 +122,367 @@ func (r *DashboardReconciler) applyDashboard(ctx context.Context, dashboard *daw
 		return ctrl.Result{}, nil
 	}
 
-	generator, err := r.generatorStore.Load(ctx, generatorURL)
+	if err := r.checkPinned(generatorURL); err != nil {
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionGeneratorFetched,
+			reasonNotPinned,
+			"Generator reference is refused by the pinning policy",
+			err,
+			logger,
+		)
+		// The reference won't be pinned until the dashboard is updated.
+		return ctrl.Result{}, nil
+	}
+
+	credentials, err := r.registryCredentials(ctx, r.apiReader, dashboard.Namespace, dashboard.Spec.ImagePullSecrets)
 	if err != nil {
 		r.setFailureStatus(
 			ctx,
 			dashboard,
-			"Could not retrieve referenced generator",
+			dawgv1.DashboardConditionGeneratorFetched,
+			reasonCredentialsFailed,
+			"Could not resolve registry credentials",
 			err,
 			logger,
 		)
 		return ctrl.Result{}, err
 	}
 
-	genResult, err := r.runtime.Execute(ctx, generator, []byte(dashboard.Spec.Config))
+	gen, err := r.generatorStore.Load(generator.WithCredentials(ctx, credentials), generatorURL)
 	if err != nil {
+		reason, message := classifyLoadError(err)
+
 		r.setFailureStatus(
 			ctx,
 			dashboard,
-			"Could not execute generator",
+			dawgv1.DashboardConditionGeneratorFetched,
+			reason,
+			message,
 			err,
 			logger,
 		)
+		return ctrl.Result{}, err
+	}
+
+	if resolved := gen.ResolvedDigest.String(); resolved != dashboard.Status.GeneratorDigest {
+		if dashboard.Status.GeneratorDigest != "" {
+			logger.Info("Generator reference resolved to a new digest", "previous", dashboard.Status.GeneratorDigest, "digest", resolved)
+		}
+
+		dashboard.Status.GeneratorDigest = resolved
+	}
+
+	r.setCondition(dashboard, dawgv1.DashboardConditionGeneratorFetched, metav1.ConditionTrue, reasonFetched, "Fetched generator "+dashboard.Spec.Generator)
 
+	config, err := generatorConfig(ctx, r.apiReader, dashboard.Namespace, dashboard.Spec.Config, dashboard.Spec.ConfigFrom)
+	if err != nil {
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionGenerated,
+			reasonConfigFailed,
+			"Could not resolve the dashboard config",
+			err,
+			logger,
+		)
 		return ctrl.Result{}, err
 	}
 
-	dashboardResult, err := r.grafana.CreateDashboard(
-		ctx,
-		&grafana.CreateDashboardRequest{
-			Dashboard: genResult.Payload,
-			Overwrite: true,
-		},
-	)
+	// Targets are resolved before running the generator, which is told about the Grafana it generates for.
+	targets, err := r.grafanaTargets(ctx, dashboard)
 	if err != nil {
 		r.setFailureStatus(
 			ctx,
 			dashboard,
-			"Could not create or update the dashboard in Grafana",
+			dawgv1.DashboardConditionSynced,
+			reasonInstancesFailed,
+			"Could not resolve the Grafana instances of the dashboard",
 			err,
 			logger,
 		)
-		// TODO be clever here: only retry if it makes sense.
 		return ctrl.Result{}, err
 	}
 
-	r.setSuccessStatus(ctx, dashboard, dashboardResult, logger)
+	var generated []*generatedDashboard
 
-	logger.Info("Applied dashboard", "grafana_id", dashboardResult.ID)
+	for _, runTargets := range r.generatorRuns(targets) {
+		out, retry, err := r.generate(ctx, dashboard, gen, config, runTargets, logger)
+		if err != nil {
+			if !retry {
+				return ctrl.Result{}, nil
+			}
 
-	return ctrl.Result{}, nil
+			return ctrl.Result{}, err
+		}
+
+		generated = append(generated, out)
+	}
+
+	r.setCondition(dashboard, dawgv1.DashboardConditionGenerated, metav1.ConditionTrue, reasonGenerated, "Generated dashboard")
+
+	var (
+		checked, drifted bool
+		applyErrs        []error
+	)
+
+	for _, out := range generated {
+		for _, target := range out.targets {
+			drift, err := r.applyToTarget(ctx, dashboard, target, out.payload, out.resources, targetLogger(logger, target))
+			if err != nil {
+				// An unreachable Grafana must not prevent the dashboard from being applied to the others.
+				applyErrs = append(applyErrs, err)
+				continue
+			}
+
+			checked = checked || drift != nil
+			drifted = drifted || (drift != nil && drift.reason != driftReasonInSync)
+		}
+	}
+
+	if err := r.deleteStaleTargets(ctx, dashboard, targets, logger); err != nil {
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionSynced,
+			reasonGrafanaFailed,
+			"Could not delete the dashboard from the Grafana instances it does not target anymore",
+			err,
+			logger,
+		)
+		return ctrl.Result{}, errors.Join(append(applyErrs, err)...)
+	}
+
+	if len(applyErrs) > 0 {
+		// The failures are already reported, record the servers the dashboard has been applied to since.
+		if err := r.k8sClient.Status().Update(ctx, dashboard); err != nil {
+			logger.Error(err, "Could not update dashboard status")
+		}
+
+		return ctrl.Result{}, errors.Join(applyErrs...)
+	}
+
+	if checked && !drifted {
+		meta.SetStatusCondition(&dashboard.Status.Conditions, metav1.Condition{
+			Type:               dawgv1.DashboardConditionDrifted,
+			Status:             metav1.ConditionFalse,
+			Reason:             driftReasonInSync,
+			Message:            "Grafana dashboard matches the generated one",
+			ObservedGeneration: dashboard.Generation,
+		})
+	}
+
+	r.setSuccessStatus(ctx, dashboard, logger)
+
+	logger.Info("Applied dashboard", "grafana_instances", len(targets))
+
+	// Requeued to detect drift in Grafana, as changes made there do not trigger any event.
+	return ctrl.Result{RequeueAfter: r.resyncInterval}, nil
 }
 
-func (r *DashboardReconciler) deleteDashboard(ctx context.Context, dashboard *dawgv1.Dashboard, logger logr.Logger) (ctrl.Result, error) {
-	if !controllerutil.ContainsFinalizer(dashboard, finalizer) {
-		return ctrl.Result{}, nil
+// generatedDashboard is the output of the generator for a set of Grafana servers.
+type generatedDashboard struct {
+	targets   []grafanaTarget
+	payload   json.RawMessage
+	resources []generator.Resource
+}
+
+// generatorRuns groups the Grafana servers by run of the generator. The generator runs once for all the servers,
+// unless it is told about the Grafana it generates for: it then runs once per server, with the facts of this server.
+func (r *DashboardReconciler) generatorRuns(targets []grafanaTarget) [][]grafanaTarget {
+	if !r.grafanaContext || len(targets) <= 1 {
+		return [][]grafanaTarget{targets}
 	}
 
-	if dashboard.Status.SyncStatus != dawgv1.DashboardStatusOK {
-		logger.Info("Deleting a NOK dashboard, only removing the finalizer")
+	runs := make([][]grafanaTarget, len(targets))
 
-		controllerutil.RemoveFinalizer(dashboard, finalizer)
-		if err := r.k8sClient.Update(ctx, dashboard); err != nil {
-			return ctrl.Result{}, err
+	for i, target := range targets {
+		runs[i] = []grafanaTarget{target}
+	}
+
+	return runs
+}
+
+// generate runs the generator of a dashboard for the given Grafana servers.
+// On failure, it reports it in the dashboard status and tells if running the generator again might help.
+func (r *DashboardReconciler) generate(
+	ctx context.Context,
+	dashboard *dawgv1.Dashboard,
+	gen *generator.Generator,
+	config []byte,
+	targets []grafanaTarget,
+	logger logr.Logger,
+) (*generatedDashboard, bool, error) {
+	if len(targets) == 1 {
+		logger = targetLogger(logger, targets[0])
+	}
+
+	genCtx, err := r.dashboardContext(ctx, dashboard, targets)
+	if err != nil {
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionGenerated,
+			reasonGrafanaFailed,
+			"Could not describe Grafana to the generator",
+			err,
+			logger,
+		)
+		return nil, true, err
+	}
+
+	genResult, err := r.runtime.Execute(generator.WithExecutionContext(ctx, genCtx), gen, config)
+	if err != nil {
+		logExecutionErrorOutput(logger, err)
+
+		failure := classifyExecutionError(err)
+
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionGenerated,
+			failure.reason,
+			failure.message,
+			err,
+			logger,
+		)
+
+		return nil, failure.retry, err
+	}
+
+	logGeneratorOutput(logger, genResult.Logs, genResult.Stdout, genResult.Stderr)
+
+	payload, resources, err := splitResources(genResult.AllResources())
+	if err != nil {
+		r.setFailureStatus(
+			ctx,
+			dashboard,
+			dawgv1.DashboardConditionGenerated,
+			reasonInvalidOutput,
+			"Generator output is invalid",
+			err,
+			logger,
+		)
+		// The output won't change until the generator or its config does.
+		return nil, false, err
+	}
+
+	return &generatedDashboard{targets: targets, payload: payload, resources: resources}, false, nil
+}
+
+func targetLogger(logger logr.Logger, target grafanaTarget) logr.Logger {
+	if target.instance == "" {
+		return logger
+	}
+
+	return logger.WithValues("instance", target.instance)
+}
+
+// applyToTarget applies the generated dashboard and resources to a Grafana server, and records them in the dashboard status.
+// It returns how the dashboard drifted in this server, nil if it was not checked for drift.
+func (r *DashboardReconciler) applyToTarget(
+	ctx context.Context,
+	dashboard *dawgv1.Dashboard,
+	target grafanaTarget,
+	dashboardPayload json.RawMessage,
+	resources []generator.Resource,
+	logger logr.Logger,
+) (*dashboardDrift, error) {
+	var (
+		state                           = targetStatus(dashboard, target.instance)
+		resourcesBefore, resourcesAfter = splitGeneratedResources(resources)
+		managed                         []dawgv1.ManagedResource
+	)
+
+	// fail records the resources applied so far, so they can be cleaned up later, then reports the failed step.
+	fail := func(reason, message string, err error) error {
+		if target.instance != "" {
+			message += " of GrafanaInstance " + target.instance
 		}
 
-		return ctrl.Result{}, nil
+		state.Resources = mergeResources(state.Resources, managed)
+		setTargetStatus(dashboard, state)
+
+		r.setFailureStatus(ctx, dashboard, dawgv1.DashboardConditionSynced, reason, message, err, logger)
+
+		return err
+	}
+
+	applied, err := r.applyResources(ctx, target.client, resourcesBefore, state.Resources)
+	managed = append(managed, applied...)
+	if err != nil {
+		return nil, fail(reasonResourcesFailed, "Could not apply generated resources", err)
 	}
 
-	logger.Info("Deleting Dashboard")
+	if err := checkLibraryPanels(ctx, target.client, dashboardPayload, managed); err != nil {
+		return nil, fail(reasonLibraryPanelsMissing, "Dashboard links to library panels missing from Grafana", err)
+	}
 
-	_, err := r.grafana.DeleteDashboard(ctx, &grafana.DeleteDashboardRequest{UID: dashboard.Status.Grafana.UID})
+	folderUID, err := r.resolveFolder(ctx, dashboard, target)
 	if err != nil {
-		return ctrl.Result{}, err
+		return nil, fail(reasonFolderFailed, "Could not resolve the dashboard folder", err)
+	}
+
+	dashboardResult, drift, err := r.syncDashboard(ctx, dashboard, target, &state, dashboardPayload, folderUID, logger)
+	if err != nil {
+		// TODO be clever here: only retry if it makes sense.
+		return nil, fail(reasonGrafanaFailed, "Could not create or update the dashboard in Grafana", err)
+	}
+
+	applied, err = r.applyResources(ctx, target.client, resourcesAfter, state.Resources)
+	managed = append(managed, applied...)
+	if err != nil {
+		return nil, fail(reasonResourcesFailed, "Could not apply generated resources", err)
+	}
+
+	if err := r.deleteResources(ctx, dashboard, target.client, staleResources(state.Resources, managed)); err != nil {
+		return nil, fail(reasonResourcesFailed, "Could not delete resources not generated anymore", err)
+	}
+
+	state.Resources = managed
+	state.Grafana = dawgv1.GrafanaInfo{
+		ID:        dashboardResult.ID,
+		UID:       dashboardResult.UID,
+		Version:   dashboardResult.Version,
+		URL:       dashboardResult.URL,
+		Slug:      dashboardResult.Slug,
+		FolderUID: folderUID,
+	}
+
+	setTargetStatus(dashboard, state)
+
+	logger.Info("Applied dashboard to Grafana", "grafana_id", dashboardResult.ID, "resources", len(managed))
+
+	return drift, nil
+}
+
+// deleteStaleTargets deletes the dashboard from the Grafana servers it is not applied to anymore.
+func (r *DashboardReconciler) deleteStaleTargets(ctx context.Context, dashboard *dawgv1.Dashboard, targets []grafanaTarget, logger logr.Logger) error {
+	for _, state := range appliedStatuses(dashboard) {
+		if slices.ContainsFunc(targets, func(target grafanaTarget) bool { return target.instance == state.Name }) {
+			continue
+		}
+
+		if err := r.deleteFromTarget(ctx, dashboard, state, logger); err != nil {
+			return err
+		}
+
+		removeTargetStatus(dashboard, state.Name)
+	}
+
+	return nil
+}
+
+func (r *DashboardReconciler) deleteDashboard(ctx context.Context, dashboard *dawgv1.Dashboard, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(dashboard, finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	for _, state := range appliedStatuses(dashboard) {
+		if err := r.deleteFromTarget(ctx, dashboard, state, logger); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	controllerutil.RemoveFinalizer(dashboard, finalizer)
@@ -173,43 +495,193 @@ func (r *DashboardReconciler) deleteDashboard(ctx context.Context, dashboard *da
 	return ctrl.Result{}, nil
 }
 
-func (r *DashboardReconciler) setSuccessStatus(ctx context.Context, dashboard *dawgv1.Dashboard, grafanaResponse *grafana.CreateDashboardResponse, logger logr.Logger) {
-	dashboard.Status.SyncStatus = string(dawgv1.DashboardStatusOK)
-	dashboard.Status.Grafana.ID = grafanaResponse.ID
-	dashboard.Status.Grafana.UID = grafanaResponse.UID
-	dashboard.Status.Grafana.URL = grafanaResponse.URL
-	dashboard.Status.Grafana.Version = grafanaResponse.Version
-	dashboard.Status.Grafana.Slug = grafanaResponse.Slug
-	dashboard.Status.Error = ""
+// deleteFromTarget deletes the dashboard and its resources from a Grafana server it has been applied to.
+func (r *DashboardReconciler) deleteFromTarget(ctx context.Context, dashboard *dawgv1.Dashboard, state dawgv1.DashboardInstanceStatus, logger logr.Logger) error {
+	if state.Name != "" {
+		logger = logger.WithValues("instance", state.Name)
+	}
+
+	target, ok, err := r.instanceTarget(ctx, types.NamespacedName{Namespace: dashboard.Namespace, Name: state.Name})
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		logger.Info("Grafana instance does not exist anymore, not deleting the dashboard from it")
+		return nil
+	}
+
+	resourcesBefore, resourcesAfter := splitManagedResources(state.Resources)
+
+	if err := r.deleteResources(ctx, dashboard, target.client, resourcesAfter); err != nil {
+		return err
+	}
+
+	if state.Grafana.UID == "" {
+		logger.Info("Deleting a NOK dashboard, not deleting the Grafana dashboard")
+	} else {
+		logger.Info("Deleting Dashboard")
 
-	if err := r.k8sClient.Status().Update(ctx, dashboard); err != nil {
-		logger.Error(err, "Could not update dashboard status")
+		_, err := target.client.DeleteDashboard(ctx, &grafana.DeleteDashboardRequest{UID: state.Grafana.UID})
+		if err != nil && !grafana.IsNotFound(err) {
+			return err
+		}
 	}
+
+	return r.deleteResources(ctx, dashboard, target.client, resourcesBefore)
 }
 
-func (r *DashboardReconciler) setFailureStatus(ctx context.Context, dashboard *dawgv1.Dashboard, message string, err error, logger logr.Logger) {
-	logger.Error(err, message)
+// logGeneratorOutput forwards what a generator logged and wrote to its standard streams.
+func logGeneratorOutput(logger logr.Logger, logs []generator.LogEntry, stdout, stderr []byte) {
+	for _, entry := range logs {
+		verbosity := 0
+		if entry.Level == gdk.LogLevelDebug {
+			verbosity = 1
+		}
+
+		logger.V(verbosity).Info("Generator log", "level", entry.Level.String(), "message", entry.Message)
+	}
 
-	dashboard.Status.SyncStatus = string(dawgv1.DashboardStatusError)
-	dashboard.Status.Grafana = dawgv1.GrafanaInfo{}
-	dashboard.Status.Error = err.Error()
+	for _, stream := range []struct {
+		name   string
+		output []byte
+	}{
+		{name: "stdout", output: stdout},
+		{name: "stderr", output: stderr},
+	} {
+		for _, line := range strings.Split(strings.TrimRight(string(stream.output), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+
+			logger.Info("Generator output", "stream", stream.name, "line", line)
+		}
+	}
+}
+
+func logExecutionErrorOutput(logger logr.Logger, err error) {
+	var execErr *generator.ExecutionError
+	if errors.As(err, &execErr) {
+		logGeneratorOutput(logger, execErr.Logs, execErr.Stdout, execErr.Stderr)
+	}
+}
+
+// classifyLoadError tells why a generator could not be loaded, loading is always retried.
+func classifyLoadError(err error) (string, string) {
+	if errors.Is(err, generator.ErrUnverifiedGenerator) {
+		// Retried, as the generator might be signed later on.
+		return reasonUnverified, "Generator signature could not be verified"
+	}
+
+	return reasonFetchFailed, "Could not retrieve referenced generator"
+}
+
+// executionFailure describes why a generator failed to run, and if running it again might help.
+type executionFailure struct {
+	reason  string
+	message string
+	retry   bool
+}
+
+func classifyExecutionError(err error) executionFailure {
+	var (
+		configErr *generator.ConfigValidationError
+		gdkErr    *gdk.RuntimeError
+	)
+
+	if errors.As(err, &configErr) {
+		// The config won't become valid until the resource is updated.
+		return executionFailure{reason: reasonInvalidConfig, message: "Config does not match the generator schema"}
+	}
+
+	if limit, ok := exceededLimit(err); ok {
+		return executionFailure{
+			reason:  reasonLimitExceeded,
+			message: "Generator exceeded its " + limit + " limit",
+			// Timeouts might be transient, other limits won't change until the generator does.
+			retry: limit == limitInstantiateTimeout || limit == limitExecuteTimeout,
+		}
+	}
+
+	return executionFailure{
+		reason:  reasonExecutionFailed,
+		message: "Could not execute generator",
+		// Do not retry if the generator reports that running it again won't help, eg: invalid config.
+		retry: !errors.As(err, &gdkErr) || gdkErr.Retryable(),
+	}
+}
+
+const (
+	limitInstantiateTimeout = "instantiate timeout"
+	limitExecuteTimeout     = "execute timeout"
+	limitMemory             = "memory"
+	limitOutputSize         = "output size"
+)
+
+// exceededLimit tells which execution limit a generator error reports, if any.
+func exceededLimit(err error) (string, bool) {
+	var (
+		instantiateTimeoutErr *generator.InstantiateTimeoutError
+		executeTimeoutErr     *generator.ExecuteTimeoutError
+		memoryErr             *generator.MemoryLimitError
+		outputSizeErr         *generator.OutputSizeError
+	)
 
-	if err := r.k8sClient.Status().Update(ctx, dashboard); err != nil {
-		logger.Error(err, "Could not update dashboard status")
+	switch {
+	case errors.As(err, &instantiateTimeoutErr):
+		return limitInstantiateTimeout, true
+	case errors.As(err, &executeTimeoutErr):
+		return limitExecuteTimeout, true
+	case errors.As(err, &memoryErr):
+		return limitMemory, true
+	case errors.As(err, &outputSizeErr):
+		return limitOutputSize, true
+	default:
+		return "", false
 	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DashboardReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.k8sClient = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&dawgv1.Dashboard{}).
-		// Do not process status updates.
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
-		// Do not process delete events as we're using finalizers.
-		WithEventFilter(predicate.Funcs{
-			DeleteFunc: func(e event.DeleteEvent) bool { return false },
-		}).
+		For(
+			&dawgv1.Dashboard{},
+			// Do not process status updates, nor delete events as we're using finalizers.
+			builder.WithPredicates(
+				predicate.GenerationChangedPredicate{},
+				predicate.Funcs{
+					DeleteFunc: func(e event.DeleteEvent) bool { return false },
+				},
+			),
+		).
+		// Dashboards selecting instances by label must follow their labels too.
+		Watches(
+			&dawgv1.GrafanaInstance{},
+			handler.EnqueueRequestsFromMapFunc(r.instanceDashboards),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{})),
+		).
+		// Dashboards waiting for library panels are applied once the panels change.
+		Watches(
+			&dawgv1.LibraryPanel{},
+			handler.EnqueueRequestsFromMapFunc(r.libraryPanelDashboards),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		// Config sources are read from the API server, only their metadata is cached to know when they change.
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.configSourceDashboards(false)),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.configSourceDashboards(true)),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
 		Complete(r)
 }
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -21,6 +22,8 @@ import (
 	"github.com/jlevesy/dawg/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,6 +37,18 @@ var v1Bin []byte
 //go:embed testdata/v2.wasm
 var v2Bin []byte
 
+//go:generate tinygo build -o ./testdata/multi.wasm -scheduler=none --no-debug -target wasi ./testdata/multi
+//go:embed testdata/multi.wasm
+var multiBin []byte
+
+//go:generate tinygo build -o ./testdata/datasource.wasm -scheduler=none --no-debug -target wasi ./testdata/datasource
+//go:embed testdata/datasource.wasm
+var datasourceBin []byte
+
+//go:generate tinygo build -o ./testdata/folders.wasm -scheduler=none --no-debug -target wasi ./testdata/folders
+//go:embed testdata/folders.wasm
+var foldersBin []byte
+
 var store = fakeStore{
 	"fake://foo/bar/biz:v1": {
 		Bin: v1Bin,
@@ -41,9 +56,966 @@ var store = fakeStore{
 	"fake://foo/bar/biz:v2": {
 		Bin: v2Bin,
 	},
+	"fake://foo/bar/biz:multi": {
+		Bin: multiBin,
+	},
+	"fake://foo/bar/biz:datasource": {
+		Bin: datasourceBin,
+	},
+	"fake://foo/bar/biz:folders": {
+		Bin: foldersBin,
+	},
+}
+
+func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/dashboards/uid/dashboard-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"title": "foo", "message":"bar","id":42}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(store, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	// Create a dashboard resource.
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should trigger a call to Grafana.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	// Assert that our dashboard has been created on Grafana's side.
+	var req grafana.CreateDashboardRequest
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 0)).Decode(&req)
+	require.NoError(t, err)
+
+	assert.True(t, req.Overwrite)
+	assert.Equal(t, `{"version":"v1"}`, string(req.Dashboard))
+
+	// Assert that the resource status has been updated.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      dashboard.Name,
+				Namespace: dashboard.Namespace,
+			},
+			&dashboard,
+		)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	assert.Equal(t, string(dawgv1.DashboardStatusOK), dashboard.Status.SyncStatus)
+	assert.Equal(t, 345, dashboard.Status.Grafana.ID)
+	assert.Equal(t, "dashboard-uid", dashboard.Status.Grafana.UID)
+	assert.Equal(t, 42, dashboard.Status.Grafana.Version)
+	assert.Equal(t, "/url", dashboard.Status.Grafana.URL)
+	assert.Equal(t, dashboard.Generation, dashboard.Status.ObservedGeneration)
+
+	for _, conditionType := range []string{
+		dawgv1.DashboardConditionGeneratorFetched,
+		dawgv1.DashboardConditionGenerated,
+		dawgv1.DashboardConditionSynced,
+		dawgv1.DashboardConditionReady,
+	} {
+		assert.True(t, meta.IsStatusConditionTrue(dashboard.Status.Conditions, conditionType), conditionType)
+	}
+
+	// Update the resource to use a new generator.
+	dashboard.Spec.Generator = "fake://foo/bar/biz:v2"
+
+	err = k8sClient.Update(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should trigger a call to Grafana.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	// Assert that our dashboard has been updated on Grafana's side.
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 1)).Decode(&req)
+	require.NoError(t, err)
+
+	assert.True(t, req.Overwrite)
+	assert.Equal(t, `{"version":"v2"}`, string(req.Dashboard))
+
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should trigger a call to Grafana.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	//  This call deletes the dasbhoard based on its UID
+	deleteRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/dashboards/uid/dashboard-uid", deleteRequest.URL.Path)
+}
+
+func TestDashboardController_AppliesGeneratedResources(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/v1/provisioning/alert-rules/rule-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 12, "uid":"rule-uid","title":"rule"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/dashboards/uid/dashboard-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"title": "foo", "message":"bar","id":42}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(store, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:multi",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should trigger a call to create the dashboard, then a call to upsert the alert rule.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	var req grafana.CreateDashboardRequest
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 0)).Decode(&req)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":"multi"}`, string(req.Dashboard))
+
+	ruleRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodPut, ruleRequest.Method)
+	assert.Equal(t, "/api/v1/provisioning/alert-rules/rule-uid", ruleRequest.URL.Path)
+	assert.JSONEq(t, `{"uid":"rule-uid","title":"rule"}`, readAll(t, grafanaBackend.readRequestBody(t, 1)))
+
+	// Assert that the generated resources are tracked in the status.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      dashboard.Name,
+				Namespace: dashboard.Namespace,
+			},
+			&dashboard,
+		)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	assert.Equal(
+		t,
+		[]dawgv1.ManagedResource{{Kind: "alert-rule", UID: "rule-uid"}},
+		dashboard.Status.Resources,
+	)
+
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should delete the alert rule, then the dashboard.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRuleRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodDelete, deleteRuleRequest.Method)
+	assert.Equal(t, "/api/v1/provisioning/alert-rules/rule-uid", deleteRuleRequest.URL.Path)
+
+	deleteDashboardRequest := grafanaBackend.readRequest(t, 3)
+	assert.Equal(t, http.MethodDelete, deleteDashboardRequest.Method)
+	assert.Equal(t, "/api/dashboards/uid/dashboard-uid", deleteDashboardRequest.URL.Path)
+}
+
+func TestDashboardController_KeepsSharedGeneratedResources(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	// notFoundOnce answers not found to the first call, then the given status and body.
+	notFoundOnce := func(statusCode int, body string) func() *http.Response {
+		var calls int
+
+		return func() *http.Response {
+			calls++
+
+			if calls == 1 {
+				return &http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       io.NopCloser(strings.NewReader(`{"message":"not found"}`)),
+				}
+			}
+
+			return &http.Response{
+				StatusCode: statusCode,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}
+		}
+	}
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/dashboards/uid/dashboard-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"title": "foo", "message":"bar","id":42}`)),
+					}
+				},
+				"http://somegrafana.com/api/folders": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"id": 1, "uid":"generated-folder","title":"Generated"}`)),
+					}
+				},
+				"http://somegrafana.com/api/folders/generated-folder": notFoundOnce(
+					http.StatusOK,
+					`{"id": 1, "uid":"generated-folder","title":"Generated"}`,
+				),
+				"http://somegrafana.com/api/library-elements": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"result":{"uid":"generated-panel","name":"panel","version":1}}`)),
+					}
+				},
+				"http://somegrafana.com/api/library-elements/generated-panel": notFoundOnce(
+					http.StatusOK,
+					`{"result":{"uid":"generated-panel","name":"panel","version":1}}`,
+				),
+				// Grafana refuses to delete a library panel used by dashboards.
+				"DELETE http://somegrafana.com/api/library-elements/generated-panel": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusForbidden,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"the library element has connections"}`)),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(store, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:folders",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&dashboard), &dashboard)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	// The dashboard created both the folder and the library panel.
+	assert.Equal(
+		t,
+		[]dawgv1.ManagedResource{
+			{Kind: "folder", UID: "generated-folder", Created: true},
+			{Kind: "library-panel", UID: "generated-panel", Created: true},
+		},
+		dashboard.Status.Resources,
+	)
+
+	// Another dashboard generates the same folder, which already exists.
+	other := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:folders",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &other)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&other), &other)
+		require.NoError(t, err)
+		return other.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	assert.Equal(
+		t,
+		[]dawgv1.ManagedResource{
+			{Kind: "folder", UID: "generated-folder"},
+			{Kind: "library-panel", UID: "generated-panel"},
+		},
+		other.Status.Resources,
+	)
+
+	// Deleting the first dashboard leaves the library panel still in use, and the folder used by the other dashboard.
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		return grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/dashboards/uid/dashboard-uid")
+	})
+
+	assert.True(t, grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/library-elements/generated-panel"))
+	assert.False(t, grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/folders/generated-folder"))
+
+	// The other dashboard didn't create the folder, it is left in place too.
+	err = k8sClient.Delete(ctx, &other)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		var dashboards dawgv1.DashboardList
+		err = k8sClient.List(ctx, &dashboards)
+		require.NoError(t, err)
+		return len(dashboards.Items) == 0
+	})
+
+	assert.False(t, grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/folders/generated-folder"))
+}
+
+func TestDashboardController_MovesDashboardToFolder(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	folderResponse := func(uid string) func() *http.Response {
+		return func() *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(
+					strings.NewReader(
+						`{"id": 1, "uid":"` + uid + `","title":"` + uid + `"}`,
+					),
+				),
+			}
+		}
+	}
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/folders/team-folder":  folderResponse("team-folder"),
+				"http://somegrafana.com/api/folders/other-folder": folderResponse("other-folder"),
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(store, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+			Folder:    &dawgv1.FolderReference{UID: "team-folder"},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should get the folder, then create the dashboard in it.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	folderRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodGet, folderRequest.Method)
+	assert.Equal(t, "/api/folders/team-folder", folderRequest.URL.Path)
+
+	var req grafana.CreateDashboardRequest
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 1)).Decode(&req)
+	require.NoError(t, err)
+	assert.Equal(t, "team-folder", req.FolderUID)
+	assert.Equal(t, `{"version":"v1"}`, string(req.Dashboard))
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      dashboard.Name,
+				Namespace: dashboard.Namespace,
+			},
+			&dashboard,
+		)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	assert.Equal(t, "team-folder", dashboard.Status.Grafana.FolderUID)
+
+	// Move the dashboard to another folder.
+	dashboard.Spec.Folder = &dawgv1.FolderReference{UID: "other-folder"}
+
+	err = k8sClient.Update(ctx, &dashboard)
+	require.NoError(t, err)
+
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	// The dashboard UID is set, so that Grafana moves the dashboard instead of creating a new one.
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 3)).Decode(&req)
+	require.NoError(t, err)
+	assert.Equal(t, "other-folder", req.FolderUID)
+	assert.JSONEq(t, `{"uid":"dashboard-uid","version":"v1"}`, string(req.Dashboard))
+}
+
+func TestDashboardController_AppliesToGrafanaInstances(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	dashboardResponse := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(
+				strings.NewReader(
+					`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+				),
+			),
+		}
+	}
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://grafana-a.com/api/dashboards/db":                dashboardResponse,
+				"http://grafana-b.com/api/dashboards/db":                dashboardResponse,
+				"http://grafana-a.com/api/dashboards/uid/dashboard-uid": dashboardResponse,
+				"http://grafana-b.com/api/dashboards/uid/dashboard-uid": dashboardResponse,
+			},
+		}
+
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			// No Grafana for the controller, dashboards must reference instances.
+			controller.NewDashboardReconciller(
+				store,
+				genRuntime,
+				nil,
+				controller.WithGrafanaClientOptions(grafana.WithRoundTripper(&grafanaBackend)),
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	err = k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-tokens", Namespace: "default"},
+		Data: map[string][]byte{
+			"a": []byte("token-a"),
+			"b": []byte("token-b"),
+		},
+	})
+	require.NoError(t, err)
+
+	for _, instance := range []dawgv1.GrafanaInstance{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Labels: map[string]string{"env": "prod"}},
+			Spec: dawgv1.GrafanaInstanceSpec{
+				URL:   "http://grafana-a.com",
+				OrgID: 2,
+				TokenSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+					Key:                  "a",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Labels: map[string]string{"env": "prod"}},
+			Spec: dawgv1.GrafanaInstanceSpec{
+				URL: "http://grafana-b.com",
+				TokenSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+					Key:                  "b",
+				},
+			},
+		},
+	} {
+		err = k8sClient.Create(ctx, &instance)
+		require.NoError(t, err)
+	}
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+			Grafana: &dawgv1.GrafanaInstanceReference{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should create the dashboard in both instances, in the order of their names.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	requestA := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, "grafana-a.com", requestA.URL.Host)
+	assert.Equal(t, "Bearer token-a", requestA.Header.Get("Authorization"))
+	assert.Equal(t, "2", requestA.Header.Get("X-Grafana-Org-Id"))
+
+	requestB := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, "grafana-b.com", requestB.URL.Host)
+	assert.Equal(t, "Bearer token-b", requestB.Header.Get("Authorization"))
+	assert.Empty(t, requestB.Header.Get("X-Grafana-Org-Id"))
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      dashboard.Name,
+				Namespace: dashboard.Namespace,
+			},
+			&dashboard,
+		)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	require.Len(t, dashboard.Status.Instances, 2)
+	for i, name := range []string{"a", "b"} {
+		assert.Equal(t, name, dashboard.Status.Instances[i].Name)
+		assert.Equal(t, "dashboard-uid", dashboard.Status.Instances[i].Grafana.UID)
+	}
+	assert.Empty(t, dashboard.Status.Grafana.UID)
+
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should delete the dashboard from both instances.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	for i, host := range []string{"grafana-a.com", "grafana-b.com"} {
+		deleteRequest := grafanaBackend.readRequest(t, 2+i)
+		assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+		assert.Equal(t, host, deleteRequest.URL.Host)
+		assert.Equal(t, "/api/dashboards/uid/dashboard-uid", deleteRequest.URL.Path)
+	}
+}
+
+func TestDashboardController_FollowsGrafanaInstances(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	dashboardResponse := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(
+				strings.NewReader(
+					`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+				),
+			),
+		}
+	}
+
+	var (
+		// Grafana A is unreachable.
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://grafana-b.com/api/dashboards/db":                dashboardResponse,
+				"http://grafana-b.com/api/dashboards/uid/dashboard-uid": dashboardResponse,
+			},
+		}
+
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(
+				store,
+				genRuntime,
+				nil,
+				controller.WithGrafanaClientOptions(grafana.WithRoundTripper(&grafanaBackend)),
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	err = k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-tokens", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b"} {
+		err = k8sClient.Create(ctx, &dawgv1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"env": "prod"}},
+			Spec: dawgv1.GrafanaInstanceSpec{
+				URL: "http://grafana-" + name + ".com",
+				TokenSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+					Key:                  "token",
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+			Grafana: &dawgv1.GrafanaInstanceReference{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// Grafana A failing does not prevent the dashboard from being applied to Grafana B.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		return grafanaBackend.hasRequest(http.MethodPost, "http://grafana-b.com/api/dashboards/db")
+	})
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&dashboard), &dashboard)
+		require.NoError(t, err)
+
+		return slices.ContainsFunc(dashboard.Status.Instances, func(status dawgv1.DashboardInstanceStatus) bool {
+			return status.Name == "b" && status.Grafana.UID == "dashboard-uid"
+		})
+	})
+
+	assert.Equal(t, string(dawgv1.DashboardStatusError), dashboard.Status.SyncStatus)
+
+	// Grafana B is not selected anymore, the dashboard is deleted from it.
+	var instance dawgv1.GrafanaInstance
+
+	err = k8sClient.Get(ctx, client.ObjectKey{Name: "b", Namespace: "default"}, &instance)
+	require.NoError(t, err)
+
+	instance.Labels = map[string]string{"env": "staging"}
+
+	err = k8sClient.Update(ctx, &instance)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		return grafanaBackend.hasRequest(http.MethodDelete, "http://grafana-b.com/api/dashboards/uid/dashboard-uid")
+	})
+}
+
+func TestDashboardController_GeneratesPerGrafanaInstance(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	jsonResponse := func(body string) func() *http.Response {
+		return func() *http.Response {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}
+		}
+	}
+
+	dashboardResponse := jsonResponse(`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`)
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://grafana-a.com/api/health":      jsonResponse(`{"version":"10.2.0"}`),
+				"http://grafana-b.com/api/health":      jsonResponse(`{"version":"10.4.0"}`),
+				"http://grafana-a.com/api/datasources": jsonResponse(`[{"uid":"prom-a","name":"Prometheus","type":"prometheus"}]`),
+				"http://grafana-b.com/api/datasources": jsonResponse(
+					`[{"uid":"loki-b","name":"Loki","type":"loki"},{"uid":"prom-b","name":"Prometheus","type":"prometheus","isDefault":true}]`,
+				),
+				"http://grafana-a.com/api/dashboards/db":                dashboardResponse,
+				"http://grafana-b.com/api/dashboards/db":                dashboardResponse,
+				"http://grafana-a.com/api/dashboards/uid/dashboard-uid": dashboardResponse,
+				"http://grafana-b.com/api/dashboards/uid/dashboard-uid": dashboardResponse,
+			},
+		}
+
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(
+				store,
+				genRuntime,
+				nil,
+				controller.WithGrafanaClientOptions(grafana.WithRoundTripper(&grafanaBackend)),
+				controller.WithGrafanaContext(),
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	err = k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana-tokens", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"a", "b"} {
+		err = k8sClient.Create(ctx, &dawgv1.GrafanaInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"env": "prod"}},
+			Spec: dawgv1.GrafanaInstanceSpec{
+				URL: "http://grafana-" + name + ".com",
+				TokenSecretRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+					Key:                  "token",
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	err = k8sClient.Create(ctx, &dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:datasource",
+			Config:    "some: config",
+			Grafana: &dawgv1.GrafanaInstanceReference{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// The generator runs for each instance, after describing it: then the dashboards are created.
+	for i := 0; i < 6; i++ {
+		testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	}
+
+	for i, want := range []struct {
+		host       string
+		datasource string
+	}{
+		{host: "grafana-a.com", datasource: "prom-a"},
+		{host: "grafana-b.com", datasource: "prom-b"},
+	} {
+		request := grafanaBackend.readRequest(t, 4+i)
+		assert.Equal(t, want.host, request.URL.Host)
+		assert.Equal(t, "/api/dashboards/db", request.URL.Path)
+
+		var req grafana.CreateDashboardRequest
+		err = json.NewDecoder(grafanaBackend.readRequestBody(t, 4+i)).Decode(&req)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"datasource":"`+want.datasource+`"}`, string(req.Dashboard))
+	}
 }
 
-func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
+func TestDashboardController_ReconcilesOnConfigSourceChange(t *testing.T) {
 	ctx := context.Background()
 
 	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
@@ -76,7 +1048,7 @@ func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
 						StatusCode: http.StatusOK,
 						Body: io.NopCloser(
 							strings.NewReader(
-								`{"title": "foo", "message":"bar","id":42}`,
+								`{"dashboard":{"uid":"dashboard-uid","version":"v1"},"meta":{"version":42}}`,
 							),
 						),
 					}
@@ -96,7 +1068,137 @@ func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
 		k8sClient = mgr.GetClient()
 	)
 
-	// Create a dashboard resource.
+	baseConfig := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "base-config", Namespace: "default"},
+		Data:       map[string]string{"config.yaml": "some: base"},
+	}
+
+	err = k8sClient.Create(ctx, &baseConfig)
+	require.NoError(t, err)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "other: config",
+			ConfigFrom: []dawgv1.ConfigSource{
+				{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: baseConfig.Name},
+						Key:                  "config.yaml",
+					},
+				},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should create the dashboard.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      dashboard.Name,
+				Namespace: dashboard.Namespace,
+			},
+			&dashboard,
+		)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
+
+	// Updating the config source reconciles the dashboard again.
+	baseConfig.Data["config.yaml"] = "some: updated"
+
+	err = k8sClient.Update(ctx, &baseConfig)
+	require.NoError(t, err)
+
+	// The generated dashboard did not change, so it is only checked for drift.
+	testutil.WaitForSignal(t, 5*time.Second, grafanaBackend.reqReceived)
+
+	driftRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodGet, driftRequest.Method)
+	assert.Equal(t, "/api/dashboards/uid/dashboard-uid", driftRequest.URL.Path)
+
+	// Deleting the config source reconciles the dashboard again, which now misses its config.
+	err = k8sClient.Delete(ctx, &baseConfig)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&dashboard), &dashboard)
+		require.NoError(t, err)
+
+		generated := meta.FindStatusCondition(dashboard.Status.Conditions, dawgv1.DashboardConditionGenerated)
+		return generated != nil && generated.Reason == "ConfigFailed"
+	})
+}
+
+func TestDashboardController_RevertsDrift(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				// Someone deleted the dashboard in the Grafana UI.
+				"http://somegrafana.com/api/dashboards/uid/dashboard-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusNotFound,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"message":"Dashboard not found"}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(
+				store,
+				genRuntime,
+				grafanaClient,
+				controller.WithResyncInterval(500*time.Millisecond),
+			),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
 	dashboard := dawgv1.Dashboard{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test-dashboard",
@@ -111,18 +1213,25 @@ func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
 	err = k8sClient.Create(ctx, &dashboard)
 	require.NoError(t, err)
 
-	// This should trigger a call to Grafana.
+	// This should create the dashboard, then after the resync interval fetch it and apply it again as it drifted.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, 2*time.Second, grafanaBackend.reqReceived)
 	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
 
-	// Assert that our dashboard has been created on Grafana's side.
+	createRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPost, createRequest.Method)
+	assert.Equal(t, "/api/dashboards/db", createRequest.URL.Path)
+
+	getRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodGet, getRequest.Method)
+	assert.Equal(t, "/api/dashboards/uid/dashboard-uid", getRequest.URL.Path)
+
 	var req grafana.CreateDashboardRequest
-	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 0)).Decode(&req)
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 2)).Decode(&req)
 	require.NoError(t, err)
-
-	assert.True(t, req.Overwrite)
 	assert.Equal(t, `{"version":"v1"}`, string(req.Dashboard))
 
-	// Assert that the resource status has been updated.
+	// Assert that the drift is reported in the status.
 	testutil.Retry(t, 10, time.Second, func() bool {
 		err = k8sClient.Get(
 			ctx,
@@ -133,41 +1242,104 @@ func TestDashboardController_CreatesUpdatesDeletesDashboard(t *testing.T) {
 			&dashboard,
 		)
 		require.NoError(t, err)
-		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+		return meta.IsStatusConditionTrue(dashboard.Status.Conditions, dawgv1.DashboardConditionDrifted)
 	})
 
-	assert.Equal(t, string(dawgv1.DashboardStatusOK), dashboard.Status.SyncStatus)
-	assert.Equal(t, 345, dashboard.Status.Grafana.ID)
-	assert.Equal(t, "dashboard-uid", dashboard.Status.Grafana.UID)
-	assert.Equal(t, 42, dashboard.Status.Grafana.Version)
-	assert.Equal(t, "/url", dashboard.Status.Grafana.URL)
+	drifted := meta.FindStatusCondition(dashboard.Status.Conditions, dawgv1.DashboardConditionDrifted)
+	require.NotNil(t, drifted)
+	assert.Equal(t, "Deleted", drifted.Reason)
+}
 
-	// Update the resource to use a new generator.
-	dashboard.Spec.Generator = "fake://foo/bar/biz:v2"
+func TestDashboardController_DeletesDashboardAfterFailure(t *testing.T) {
+	ctx := context.Background()
 
-	err = k8sClient.Update(ctx, &dashboard)
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
 	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
 
-	// This should trigger a call to Grafana.
-	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/dashboards/db": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 345, "uid":"dashboard-uid","version":42,"slug":"slug","url":"/url"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/dashboards/uid/dashboard-uid": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"title": "foo", "message":"bar","id":42}`)),
+					}
+				},
+			},
+		}
 
-	// Assert that our dashboard has been updated on Grafana's side.
-	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 1)).Decode(&req)
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewDashboardReconciller(store, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
 	require.NoError(t, err)
 
-	assert.True(t, req.Overwrite)
-	assert.Equal(t, `{"version":"v2"}`, string(req.Dashboard))
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&dashboard), &dashboard)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK
+	})
 
-	err = k8sClient.Delete(ctx, &dashboard)
+	// Switch to an unknown generator, the reconciliation fails.
+	dashboard.Spec.Generator = "fake://foo/bar/biz:vbad"
+
+	err = k8sClient.Update(ctx, &dashboard)
 	require.NoError(t, err)
 
-	// This should trigger a call to Grafana.
-	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&dashboard), &dashboard)
+		require.NoError(t, err)
+		return dashboard.Status.SyncStatus == dawgv1.DashboardStatusError
+	})
 
-	//  This call deletes the dasbhoard based on its UID
-	deleteRequest := grafanaBackend.readRequest(t, 2)
-	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
-	assert.Equal(t, "/api/dashboards/uid/dashboard-uid", deleteRequest.URL.Path)
+	// The dashboard applied before the failure is still tracked.
+	assert.Equal(t, "dashboard-uid", dashboard.Status.Grafana.UID)
+
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		return grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/dashboards/uid/dashboard-uid")
+	})
 }
 
 func TestDashboardController_DeletesNOKDashboard(t *testing.T) {
@@ -276,7 +1448,11 @@ func (c *stubRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
 
 	c.reqs = append(c.reqs, r)
 
-	respBuilder, ok := c.resps[r.URL.String()]
+	// Responses can be specific to a method by prefixing the URL with it.
+	respBuilder, ok := c.resps[r.Method+" "+r.URL.String()]
+	if !ok {
+		respBuilder, ok = c.resps[r.URL.String()]
+	}
 	if !ok {
 		return nil, errRespNotFound
 	}
@@ -284,6 +1460,16 @@ func (c *stubRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	return respBuilder(), nil
 }
 
+// hasRequest tells if a request has been sent with the given method and URL.
+func (c *stubRoundtripper) hasRequest(method, url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.ContainsFunc(c.reqs, func(r *http.Request) bool {
+		return r.Method == method && r.URL.String() == url
+	})
+}
+
 func (c *stubRoundtripper) readRequest(t *testing.T, reqID int) *http.Request {
 	t.Helper()
 
@@ -315,3 +1501,12 @@ func (c *stubRoundtripper) readRequestBody(t *testing.T, reqID int) io.Reader {
 
 	return &buf
 }
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(b)
+}
@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const (
+	driftReasonInSync   = "InSync"
+	driftReasonModified = "Modified"
+	driftReasonDeleted  = "Deleted"
+	driftReasonMoved    = "Moved"
+)
+
+// dashboardDrift describes how a dashboard drifted in Grafana.
+type dashboardDrift struct {
+	reason  string
+	message string
+}
+
+// syncDashboard creates or updates the dashboard in a Grafana server. If the generated dashboard did not change since it was last applied,
+// it is only applied again when it drifted in Grafana, which is recorded in the dashboard conditions and events.
+// It returns how the dashboard drifted, nil if it was not checked for drift.
+func (r *DashboardReconciler) syncDashboard(
+	ctx context.Context,
+	dashboard *dawgv1.Dashboard,
+	target grafanaTarget,
+	state *dawgv1.DashboardInstanceStatus,
+	payload json.RawMessage,
+	folderUID string,
+	logger logr.Logger,
+) (*grafana.CreateDashboardResponse, *dashboardDrift, error) {
+	checksum := payloadChecksum(payload, folderUID)
+
+	var drift *dashboardDrift
+
+	if dashboard.Status.SyncStatus == dawgv1.DashboardStatusOK &&
+		state.Grafana.UID != "" &&
+		state.PayloadChecksum == checksum {
+		var err error
+
+		drift, err = r.detectDrift(ctx, target.client, state, payload, folderUID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not check the dashboard for drift: %w", err)
+		}
+
+		if drift.reason == driftReasonInSync {
+			return &grafana.CreateDashboardResponse{
+				ID:      state.Grafana.ID,
+				UID:     state.Grafana.UID,
+				Version: state.Grafana.Version,
+				URL:     state.Grafana.URL,
+				Slug:    state.Grafana.Slug,
+			}, drift, nil
+		}
+
+		logger.Info("Dashboard drifted in Grafana, applying it again", "reason", drift.reason)
+
+		message := drift.message
+		if target.instance != "" {
+			message += " in GrafanaInstance " + target.instance
+		}
+
+		r.recorder.Event(dashboard, corev1.EventTypeWarning, dawgv1.DashboardConditionDrifted, message)
+
+		meta.SetStatusCondition(&dashboard.Status.Conditions, metav1.Condition{
+			Type:               dawgv1.DashboardConditionDrifted,
+			Status:             metav1.ConditionTrue,
+			Reason:             drift.reason,
+			Message:            message,
+			ObservedGeneration: dashboard.Generation,
+		})
+	}
+
+	applied := payload
+
+	if state.Grafana.FolderUID != folderUID {
+		var err error
+
+		// Keep the UID of the applied dashboard if the generator did not set one.
+		// Otherwise, Grafana would create a new dashboard when moving it to another folder, instead of moving it.
+		applied, err = withUID(payload, state.Grafana.UID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resp, err := target.client.CreateDashboard(
+		ctx,
+		&grafana.CreateDashboardRequest{
+			Dashboard: applied,
+			FolderUID: folderUID,
+			Overwrite: true,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state.PayloadChecksum = checksum
+
+	return resp, drift, nil
+}
+
+// detectDrift compares the live dashboard in Grafana to the generated one.
+func (r *DashboardReconciler) detectDrift(ctx context.Context, cl *grafana.Client, state *dawgv1.DashboardInstanceStatus, payload json.RawMessage, folderUID string) (*dashboardDrift, error) {
+	live, err := cl.GetDashboard(ctx, &grafana.GetDashboardRequest{UID: state.Grafana.UID})
+	if grafana.IsNotFound(err) {
+		return &dashboardDrift{
+			reason:  driftReasonDeleted,
+			message: fmt.Sprintf("Grafana dashboard %q has been deleted", state.Grafana.UID),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if live.Meta.FolderUID != folderUID {
+		return &dashboardDrift{
+			reason:  driftReasonMoved,
+			message: fmt.Sprintf("Grafana dashboard %q has been moved to folder %q", state.Grafana.UID, live.Meta.FolderUID),
+		}, nil
+	}
+
+	matches, err := dashboardMatches(payload, live.Dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	if matches {
+		return &dashboardDrift{
+			reason:  driftReasonInSync,
+			message: "Grafana dashboard matches the generated one",
+		}, nil
+	}
+
+	return &dashboardDrift{
+		reason:  driftReasonModified,
+		message: fmt.Sprintf("Grafana dashboard %q has been modified, version %d", state.Grafana.UID, live.Meta.Version),
+	}, nil
+}
+
+// dashboardMatches tells if the live dashboard holds the generated one.
+// Only the fields set by the generator are compared, as Grafana sets some of its own.
+func dashboardMatches(generated, live json.RawMessage) (bool, error) {
+	var generatedModel, liveModel map[string]any
+
+	if err := unmarshalModel(generated, &generatedModel); err != nil {
+		return false, err
+	}
+
+	if err := unmarshalModel(live, &liveModel); err != nil {
+		return false, err
+	}
+
+	// Grafana manages those, they change every time the dashboard is saved.
+	for _, key := range []string{"id", "version"} {
+		delete(generatedModel, key)
+	}
+
+	return containsJSON(generatedModel, liveModel), nil
+}
+
+func unmarshalModel(payload json.RawMessage, model *map[string]any) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	return dec.Decode(model)
+}
+
+// containsJSON tells if got holds all the values of want. Objects in got may have more keys, arrays must have the same length.
+func containsJSON(want, got any) bool {
+	switch want := want.(type) {
+	case map[string]any:
+		gotMap, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		for key, value := range want {
+			gotValue, ok := gotMap[key]
+			if !ok || !containsJSON(value, gotValue) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		gotSlice, ok := got.([]any)
+		if !ok || len(want) != len(gotSlice) {
+			return false
+		}
+
+		for i := range want {
+			if !containsJSON(want[i], gotSlice[i]) {
+				return false
+			}
+		}
+
+		return true
+	case json.Number:
+		gotNumber, ok := got.(json.Number)
+		if !ok {
+			return false
+		}
+
+		wantFloat, wantErr := want.Float64()
+		gotFloat, gotErr := gotNumber.Float64()
+
+		return wantErr == nil && gotErr == nil && wantFloat == gotFloat
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+// payloadChecksum identifies what is applied to Grafana: the dashboard and its folder.
+func payloadChecksum(payload json.RawMessage, folderUID string) string {
+	hash := sha256.New()
+	hash.Write(payload)
+
+	if folderUID != "" {
+		hash.Write([]byte{0})
+		hash.Write([]byte(folderUID))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
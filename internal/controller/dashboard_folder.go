@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+var (
+	errInvalidFolderReference = errors.New("folder reference must set a uid, a title or a grafanaFolder, and a title to create the folder")
+	errGrafanaFolderInstance  = errors.New("GrafanaFolders are applied to the Grafana of the controller, reference the folder by uid or title instead")
+)
+
+// resolveFolder returns the UID of the folder referenced by a dashboard in a Grafana server, creating it if asked to.
+// An empty UID stands for the General folder.
+func (r *DashboardReconciler) resolveFolder(ctx context.Context, dashboard *dawgv1.Dashboard, target grafanaTarget) (string, error) {
+	ref := dashboard.Spec.Folder
+
+	switch {
+	case ref == nil:
+		return "", nil
+	case ref.GrafanaFolder != "" && target.instance != "":
+		return "", errGrafanaFolderInstance
+	case ref.GrafanaFolder != "":
+		return grafanaFolderUID(ctx, r.k8sClient, types.NamespacedName{Namespace: dashboard.Namespace, Name: ref.GrafanaFolder})
+	case ref.UID == "" && ref.Title == "", ref.Create && ref.Title == "":
+		return "", errInvalidFolderReference
+	case ref.UID != "":
+		folder, err := target.client.GetFolder(ctx, &grafana.GetFolderRequest{UID: ref.UID})
+		if grafana.IsNotFound(err) && ref.Create {
+			folder, err = target.client.CreateFolder(ctx, &grafana.CreateFolderRequest{UID: ref.UID, Title: ref.Title})
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not get folder %q: %w", ref.UID, err)
+		}
+
+		return folder.UID, nil
+	default:
+		folders, err := target.client.ListFolders(ctx, &grafana.ListFoldersRequest{})
+		if err != nil {
+			return "", fmt.Errorf("could not list folders: %w", err)
+		}
+
+		for _, folder := range folders {
+			if folder.Title == ref.Title {
+				return folder.UID, nil
+			}
+		}
+
+		if !ref.Create {
+			return "", fmt.Errorf("folder %q not found", ref.Title)
+		}
+
+		folder, err := target.client.CreateFolder(ctx, &grafana.CreateFolderRequest{Title: ref.Title})
+		if err != nil {
+			return "", fmt.Errorf("could not create folder %q: %w", ref.Title, err)
+		}
+
+		return folder.UID, nil
+	}
+}
+
+// grafanaFolderUID returns the UID of the folder managed by a GrafanaFolder, once it is ready.
+func grafanaFolderUID(ctx context.Context, reader client.Reader, key types.NamespacedName) (string, error) {
+	var folder dawgv1.GrafanaFolder
+
+	if err := reader.Get(ctx, key, &folder); err != nil {
+		return "", fmt.Errorf("could not get GrafanaFolder %s: %w", key, err)
+	}
+
+	if !meta.IsStatusConditionTrue(folder.Status.Conditions, dawgv1.GrafanaFolderConditionReady) || folder.Status.UID == "" {
+		return "", fmt.Errorf("GrafanaFolder %s is not ready", key)
+	}
+
+	return folder.Status.UID, nil
+}
+
+// withUID sets a UID in a payload, if the payload does not have one already.
+func withUID(payload json.RawMessage, uid string) (json.RawMessage, error) {
+	if uid == "" {
+		return payload, nil
+	}
+
+	var model map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &model); err != nil {
+		return nil, err
+	}
+
+	if _, ok := model["uid"]; ok {
+		return payload, nil
+	}
+
+	uidBytes, err := json.Marshal(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	model["uid"] = uidBytes
+
+	return json.Marshal(model)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+var errMissingLibraryPanel = errors.New("library panel does not exist")
+
+type dashboardPanels struct {
+	Panels []dashboardPanel `json:"panels"`
+}
+
+type dashboardPanel struct {
+	LibraryPanel *struct {
+		UID string `json:"uid"`
+	} `json:"libraryPanel"`
+	// Panels are the panels of a collapsed row.
+	Panels []dashboardPanel `json:"panels"`
+}
+
+// libraryPanelUIDs returns the UIDs of the library panels a dashboard links to, including the ones of collapsed rows.
+func libraryPanelUIDs(payload json.RawMessage) ([]string, error) {
+	var dashboard dashboardPanels
+
+	if err := json.Unmarshal(payload, &dashboard); err != nil {
+		return nil, err
+	}
+
+	var (
+		uids []string
+		walk func(panels []dashboardPanel)
+	)
+
+	walk = func(panels []dashboardPanel) {
+		for _, panel := range panels {
+			if panel.LibraryPanel != nil && panel.LibraryPanel.UID != "" {
+				uids = append(uids, panel.LibraryPanel.UID)
+			}
+
+			walk(panel.Panels)
+		}
+	}
+
+	walk(dashboard.Panels)
+
+	return uids, nil
+}
+
+// checkLibraryPanels makes sure the library panels a dashboard links to exist in a Grafana server before the dashboard is applied.
+// Library panels generated along with the dashboard were just applied, and are not checked again.
+func checkLibraryPanels(ctx context.Context, cl *grafana.Client, payload json.RawMessage, applied []dawgv1.ManagedResource) error {
+	uids, err := libraryPanelUIDs(payload)
+	if err != nil {
+		return fmt.Errorf("could not decode dashboard panels: %w", err)
+	}
+
+	for _, uid := range uids {
+		if containsResource(applied, dawgv1.ManagedResource{Kind: gdk.ResourceKindLibraryPanel, UID: uid}) {
+			continue
+		}
+
+		_, err := cl.GetLibraryElement(ctx, &grafana.GetLibraryElementRequest{UID: uid})
+		switch {
+		case grafana.IsNotFound(err):
+			return fmt.Errorf("%w: %q", errMissingLibraryPanel, uid)
+		case err != nil:
+			return fmt.Errorf("could not get library panel %q: %w", uid, err)
+		}
+	}
+
+	return nil
+}
+
+// libraryPanelDashboards enqueues the dashboards of the namespace of a LibraryPanel waiting for a library panel to exist.
+func (r *DashboardReconciler) libraryPanelDashboards(ctx context.Context, obj client.Object) []reconcile.Request {
+	var dashboards dawgv1.DashboardList
+
+	if err := r.k8sClient.List(ctx, &dashboards, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the dashboards waiting for library panels")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, dashboard := range dashboards.Items {
+		cond := meta.FindStatusCondition(dashboard.Status.Conditions, dawgv1.DashboardConditionSynced)
+		if cond != nil && cond.Reason == reasonLibraryPanelsMissing {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+// dashboardApplyOrder is the position of the dashboard when applying generated resources.
+// Resources with a lower order are applied before the dashboard, and deleted after it.
+const dashboardApplyOrder = 2
+
+var (
+	errNoUID = errors.New("resource has no uid")
+	// errResourceInUse is returned when Grafana refuses to delete a resource other resources still use.
+	errResourceInUse = errors.New("resource is still in use")
+)
+
+type resourceHandler struct {
+	order int
+	// apply creates or updates the resource, and tells if it created it.
+	apply  func(ctx context.Context, cl *grafana.Client, payload []byte) (uid string, created bool, err error)
+	delete func(ctx context.Context, cl *grafana.Client, uid string) error
+}
+
+var resourceHandlers = map[string]resourceHandler{
+	gdk.ResourceKindFolder: {
+		order:  0,
+		apply:  applyFolder,
+		delete: deleteFolder,
+	},
+	gdk.ResourceKindLibraryPanel: {
+		order:  1,
+		apply:  applyLibraryPanel,
+		delete: deleteLibraryPanel,
+	},
+	gdk.ResourceKindAlertRule: {
+		order:  3,
+		apply:  applyAlertRule,
+		delete: deleteAlertRule,
+	},
+}
+
+// splitResources extracts the dashboard from the resources produced by a generator.
+// The remaining resources are sorted in apply order.
+func splitResources(resources []generator.Resource) ([]byte, []generator.Resource, error) {
+	var (
+		dashboard []byte
+		others    = make([]generator.Resource, 0, len(resources))
+	)
+
+	for _, res := range resources {
+		if res.Kind == gdk.ResourceKindDashboard {
+			if dashboard != nil {
+				return nil, nil, errors.New("generator produced more than one dashboard")
+			}
+
+			dashboard = res.Payload
+			continue
+		}
+
+		if _, ok := resourceHandlers[res.Kind]; !ok {
+			return nil, nil, fmt.Errorf("generator produced a resource of unsupported kind %q", res.Kind)
+		}
+
+		others = append(others, res)
+	}
+
+	if dashboard == nil {
+		return nil, nil, errors.New("generator did not produce a dashboard")
+	}
+
+	sort.SliceStable(others, func(i, j int) bool {
+		return resourceHandlers[others[i].Kind].order < resourceHandlers[others[j].Kind].order
+	})
+
+	return dashboard, others, nil
+}
+
+// applyResources applies the given resources to Grafana, and returns the resources applied so far.
+// Resources created by a previous reconciliation are still reported as created by the dashboard.
+func (r *DashboardReconciler) applyResources(
+	ctx context.Context,
+	cl *grafana.Client,
+	resources []generator.Resource,
+	previous []dawgv1.ManagedResource,
+) ([]dawgv1.ManagedResource, error) {
+	managed := make([]dawgv1.ManagedResource, 0, len(resources))
+
+	for _, res := range resources {
+		uid, created, err := resourceHandlers[res.Kind].apply(ctx, cl, res.Payload)
+		if err != nil {
+			return managed, fmt.Errorf("could not apply resource of kind %q: %w", res.Kind, err)
+		}
+
+		if prev, ok := findResource(previous, res.Kind, uid); ok && prev.Created {
+			created = true
+		}
+
+		managed = append(managed, dawgv1.ManagedResource{Kind: res.Kind, UID: uid, Created: created})
+	}
+
+	return managed, nil
+}
+
+// deleteResources deletes the given resources from Grafana, in reverse apply order.
+// Deleting a folder deletes everything it holds, so folders are only deleted if the dashboard created them and nothing else uses them.
+// Resources Grafana refuses to delete because they are still in use are left in place.
+func (r *DashboardReconciler) deleteResources(ctx context.Context, dashboard *dawgv1.Dashboard, cl *grafana.Client, resources []dawgv1.ManagedResource) error {
+	logger := log.FromContext(ctx)
+
+	for i := len(resources) - 1; i >= 0; i-- {
+		res := resources[i]
+
+		handler, ok := resourceHandlers[res.Kind]
+		if !ok {
+			continue
+		}
+
+		if res.Kind == gdk.ResourceKindFolder {
+			if !res.Created {
+				logger.Info("Folder was not created by the dashboard, leaving it in place", "folder_uid", res.UID)
+				continue
+			}
+
+			user, err := r.folderUser(ctx, dashboard, res.UID)
+			if err != nil {
+				return fmt.Errorf("could not check the users of folder %q: %w", res.UID, err)
+			}
+
+			if user != "" {
+				r.recorder.Eventf(dashboard, corev1.EventTypeNormal, reasonInUse, "Folder %q is used by %s, leaving it in place", res.UID, user)
+				continue
+			}
+		}
+
+		err := handler.delete(ctx, cl, res.UID)
+		switch {
+		case err == nil, grafana.IsNotFound(err):
+		case errors.Is(err, errResourceInUse):
+			r.recorder.Eventf(
+				dashboard,
+				corev1.EventTypeWarning,
+				reasonInUse,
+				"Resource of kind %q with uid %q is still in use, leaving it in place: %s",
+				res.Kind,
+				res.UID,
+				err,
+			)
+		default:
+			return fmt.Errorf("could not delete resource of kind %q with uid %q: %w", res.Kind, res.UID, err)
+		}
+	}
+
+	return nil
+}
+
+// folderUser returns a description of a resource other than the dashboard using a folder, empty if there is none.
+// A folder is used by the GrafanaFolder managing it, and by the other dashboards generating it or placed into it.
+func (r *DashboardReconciler) folderUser(ctx context.Context, dashboard *dawgv1.Dashboard, uid string) (string, error) {
+	var folders dawgv1.GrafanaFolderList
+	if err := r.k8sClient.List(ctx, &folders); err != nil {
+		return "", err
+	}
+
+	for _, folder := range folders.Items {
+		if folderUID(&folder) == uid || folder.Status.UID == uid {
+			return "GrafanaFolder " + client.ObjectKeyFromObject(&folder).String(), nil
+		}
+	}
+
+	var dashboards dawgv1.DashboardList
+	if err := r.k8sClient.List(ctx, &dashboards); err != nil {
+		return "", err
+	}
+
+	for _, other := range dashboards.Items {
+		if client.ObjectKeyFromObject(&other) == client.ObjectKeyFromObject(dashboard) {
+			continue
+		}
+
+		for _, state := range append([]dawgv1.DashboardInstanceStatus{targetStatus(&other, "")}, other.Status.Instances...) {
+			if _, ok := findResource(state.Resources, gdk.ResourceKindFolder, uid); ok || state.Grafana.FolderUID == uid {
+				return "Dashboard " + client.ObjectKeyFromObject(&other).String(), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// staleResources returns the previously managed resources that are not managed anymore.
+func staleResources(previous, current []dawgv1.ManagedResource) []dawgv1.ManagedResource {
+	var stale []dawgv1.ManagedResource
+
+	for _, prev := range previous {
+		if !containsResource(current, prev) {
+			stale = append(stale, prev)
+		}
+	}
+
+	return stale
+}
+
+// mergeResources adds to previous the resources it does not contain yet.
+func mergeResources(previous, current []dawgv1.ManagedResource) []dawgv1.ManagedResource {
+	merged := append([]dawgv1.ManagedResource{}, previous...)
+
+	for _, res := range current {
+		if !containsResource(merged, res) {
+			merged = append(merged, res)
+		}
+	}
+
+	sortResources(merged)
+
+	return merged
+}
+
+// splitGeneratedResources splits sorted generated resources between the ones applied before and after the dashboard.
+func splitGeneratedResources(resources []generator.Resource) ([]generator.Resource, []generator.Resource) {
+	for i, res := range resources {
+		if resourceHandlers[res.Kind].order > dashboardApplyOrder {
+			return resources[:i], resources[i:]
+		}
+	}
+
+	return resources, nil
+}
+
+// splitManagedResources splits managed resources between the ones applied before and after the dashboard.
+func splitManagedResources(resources []dawgv1.ManagedResource) ([]dawgv1.ManagedResource, []dawgv1.ManagedResource) {
+	var before, after []dawgv1.ManagedResource
+
+	for _, res := range resources {
+		if resourceHandlers[res.Kind].order < dashboardApplyOrder {
+			before = append(before, res)
+		} else {
+			after = append(after, res)
+		}
+	}
+
+	return before, after
+}
+
+func sortResources(resources []dawgv1.ManagedResource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resourceHandlers[resources[i].Kind].order < resourceHandlers[resources[j].Kind].order
+	})
+}
+
+func containsResource(resources []dawgv1.ManagedResource, res dawgv1.ManagedResource) bool {
+	_, ok := findResource(resources, res.Kind, res.UID)
+	return ok
+}
+
+func findResource(resources []dawgv1.ManagedResource, kind, uid string) (dawgv1.ManagedResource, bool) {
+	for _, r := range resources {
+		if r.Kind == kind && r.UID == uid {
+			return r, true
+		}
+	}
+
+	return dawgv1.ManagedResource{}, false
+}
+
+func resourceUID(payload []byte) (string, error) {
+	var meta struct {
+		UID string `json:"uid"`
+	}
+
+	if err := json.Unmarshal(payload, &meta); err != nil {
+		return "", err
+	}
+
+	if meta.UID == "" {
+		return "", errNoUID
+	}
+
+	return meta.UID, nil
+}
+
+func applyFolder(ctx context.Context, cl *grafana.Client, payload []byte) (string, bool, error) {
+	var req grafana.CreateFolderRequest
+
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return "", false, err
+	}
+
+	if req.UID == "" {
+		return "", false, errNoUID
+	}
+
+	folder, err := cl.GetFolder(ctx, &grafana.GetFolderRequest{UID: req.UID})
+	switch {
+	case grafana.IsNotFound(err):
+		_, err = cl.CreateFolder(ctx, &req)
+		return req.UID, err == nil, err
+	case err != nil:
+		return "", false, err
+	case folder.Title != req.Title:
+		_, err = cl.UpdateFolder(
+			ctx,
+			&grafana.UpdateFolderRequest{
+				UID:       req.UID,
+				Title:     req.Title,
+				Overwrite: true,
+			},
+		)
+		return req.UID, false, err
+	default:
+		return req.UID, false, nil
+	}
+}
+
+func deleteFolder(ctx context.Context, cl *grafana.Client, uid string) error {
+	return cl.DeleteFolder(ctx, &grafana.DeleteFolderRequest{UID: uid})
+}
+
+func applyLibraryPanel(ctx context.Context, cl *grafana.Client, payload []byte) (string, bool, error) {
+	var req grafana.CreateLibraryElementRequest
+
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return "", false, err
+	}
+
+	if req.UID == "" {
+		return "", false, errNoUID
+	}
+
+	req.Kind = grafana.LibraryElementKindPanel
+
+	current, err := cl.GetLibraryElement(ctx, &grafana.GetLibraryElementRequest{UID: req.UID})
+	switch {
+	case grafana.IsNotFound(err):
+		_, err = cl.CreateLibraryElement(ctx, &req)
+		return req.UID, err == nil, err
+	case err != nil:
+		return "", false, err
+	default:
+		_, err = cl.UpdateLibraryElement(
+			ctx,
+			&grafana.UpdateLibraryElementRequest{
+				UID:       req.UID,
+				FolderUID: req.FolderUID,
+				Name:      req.Name,
+				Model:     req.Model,
+				Kind:      req.Kind,
+				Version:   current.Version,
+			},
+		)
+		return req.UID, false, err
+	}
+}
+
+func deleteLibraryPanel(ctx context.Context, cl *grafana.Client, uid string) error {
+	err := cl.DeleteLibraryElement(ctx, &grafana.DeleteLibraryElementRequest{UID: uid})
+	if grafana.IsLibraryElementInUse(err) {
+		return fmt.Errorf("%w: %w", errResourceInUse, err)
+	}
+
+	return err
+}
+
+func applyAlertRule(ctx context.Context, cl *grafana.Client, payload []byte) (string, bool, error) {
+	uid, err := resourceUID(payload)
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = cl.UpdateAlertRule(ctx, &grafana.UpdateAlertRuleRequest{UID: uid, Rule: payload})
+	if !grafana.IsNotFound(err) {
+		return uid, false, err
+	}
+
+	_, err = cl.CreateAlertRule(ctx, &grafana.CreateAlertRuleRequest{Rule: payload})
+
+	return uid, err == nil, err
+}
+
+func deleteAlertRule(ctx context.Context, cl *grafana.Client, uid string) error {
+	return cl.DeleteAlertRule(ctx, &grafana.DeleteAlertRuleRequest{UID: uid})
+}
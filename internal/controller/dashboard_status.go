@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+)
+
+// Reasons of the dashboard conditions, also used as event reasons.
+const (
+	reasonFinalizerFailed      = "FinalizerFailed"
+	reasonInvalidReference     = "InvalidReference"
+	reasonNotPinned            = "NotPinned"
+	reasonCredentialsFailed    = "CredentialsFailed"
+	reasonFetchFailed          = "FetchFailed"
+	reasonUnverified           = "Unverified"
+	reasonFetched              = "Fetched"
+	reasonConfigFailed         = "ConfigFailed"
+	reasonInvalidConfig        = "InvalidConfig"
+	reasonLimitExceeded        = "LimitExceeded"
+	reasonExecutionFailed      = "ExecutionFailed"
+	reasonInvalidOutput        = "InvalidOutput"
+	reasonGenerated            = "Generated"
+	reasonResourcesFailed      = "ResourcesFailed"
+	reasonGrafanaFailed        = "GrafanaFailed"
+	reasonInstancesFailed      = "InstancesFailed"
+	reasonFolderFailed         = "FolderFailed"
+	reasonPermissionsFailed    = "PermissionsFailed"
+	reasonConflict             = "Conflict"
+	reasonInUse                = "InUse"
+	reasonNoGrafana            = "NoGrafana"
+	reasonLibraryPanelsMissing = "LibraryPanelsMissing"
+	reasonSynced               = "Synced"
+	reasonReady                = "Ready"
+)
+
+// setCondition records the outcome of a reconciliation step, and emits an event when a step starts succeeding.
+func (r *DashboardReconciler) setCondition(dashboard *dawgv1.Dashboard, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	changed := meta.SetStatusCondition(&dashboard.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: dashboard.Generation,
+	})
+
+	// Failures are reported by setFailureStatus, every time they happen.
+	if changed && status == metav1.ConditionTrue {
+		r.recorder.Event(dashboard, corev1.EventTypeNormal, reason, message)
+	}
+}
+
+func (r *DashboardReconciler) setSuccessStatus(ctx context.Context, dashboard *dawgv1.Dashboard, logger logr.Logger) {
+	dashboard.Status.ObservedGeneration = dashboard.Generation
+	dashboard.Status.SyncStatus = string(dawgv1.DashboardStatusOK)
+	dashboard.Status.Error = ""
+	dashboard.Status.ErrorField = ""
+
+	r.setCondition(dashboard, dawgv1.DashboardConditionSynced, metav1.ConditionTrue, reasonSynced, "Dashboard is synced with Grafana")
+	r.setCondition(dashboard, dawgv1.DashboardConditionReady, metav1.ConditionTrue, reasonReady, "Dashboard is ready")
+
+	if err := r.k8sClient.Status().Update(ctx, dashboard); err != nil {
+		logger.Error(err, "Could not update dashboard status")
+	}
+}
+
+// setFailureStatus reports a failed reconciliation step, on its condition and on the Ready condition.
+func (r *DashboardReconciler) setFailureStatus(ctx context.Context, dashboard *dawgv1.Dashboard, conditionType, reason, message string, err error, logger logr.Logger) {
+	logger.Error(err, message)
+
+	dashboard.Status.ObservedGeneration = dashboard.Generation
+	// The last applied dashboard is kept, like its resources, to delete it from Grafana later on.
+	dashboard.Status.SyncStatus = string(dawgv1.DashboardStatusError)
+	dashboard.Status.Error = err.Error()
+	dashboard.Status.ErrorField = ""
+
+	var (
+		gdkErr    *gdk.RuntimeError
+		configErr *generator.ConfigValidationError
+	)
+
+	switch {
+	case errors.As(err, &gdkErr):
+		dashboard.Status.ErrorField = gdkErr.Field
+	case errors.As(err, &configErr) && len(configErr.Violations) > 0:
+		dashboard.Status.ErrorField = configErr.Violations[0].Field
+	}
+
+	conditionMessage := message + ": " + err.Error()
+
+	r.setCondition(dashboard, conditionType, metav1.ConditionFalse, reason, conditionMessage)
+	r.setCondition(dashboard, dawgv1.DashboardConditionReady, metav1.ConditionFalse, reason, conditionMessage)
+	r.recorder.Event(dashboard, corev1.EventTypeWarning, reason, conditionMessage)
+
+	if err := r.k8sClient.Status().Update(ctx, dashboard); err != nil {
+		logger.Error(err, "Could not update dashboard status")
+	}
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+)
+
+// DashboardValidator rejects invalid dashboards on admission, instead of failing later during the reconciliation.
+type DashboardValidator struct {
+	options
+
+	apiReader      client.Reader
+	generatorStore generator.Reader
+	runtime        generator.Runtime
+	dryRun         bool
+}
+
+// NewDashboardValidator returns a validator checking the generator URL and config of dashboards.
+// If dryRun is set, it also runs the generator with the dashboard config and rejects the dashboard if it fails.
+func NewDashboardValidator(store generator.Reader, runtime generator.Runtime, dryRun bool, opts ...Option) *DashboardValidator {
+	return &DashboardValidator{
+		options:        newOptions(opts),
+		generatorStore: store,
+		runtime:        runtime,
+		dryRun:         dryRun,
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-dawg-urcloud-cc-v1-dashboard,mutating=false,failurePolicy=fail,sideEffects=None,groups=dawg.urcloud.cc,resources=dashboards,verbs=create;update,versions=v1,name=vdashboard.dawg.urcloud.cc,admissionReviewVersions=v1
+
+// ValidateCreate validates a dashboard on creation.
+func (v *DashboardValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(ctx, obj)
+}
+
+// ValidateUpdate validates a dashboard on update.
+// Updates leaving the spec untouched, such as the controller adding or removing its finalizer, are always admitted:
+// otherwise an unreachable registry or a stricter controller configuration could prevent a dashboard from being deleted.
+func (v *DashboardValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldDashboard, oldOK := oldObj.(*dawgv1.Dashboard)
+	newDashboard, newOK := newObj.(*dawgv1.Dashboard)
+
+	if oldOK && newOK &&
+		(!newDashboard.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldDashboard.Spec, newDashboard.Spec)) {
+		return nil, nil
+	}
+
+	return nil, v.validate(ctx, newObj)
+}
+
+// ValidateDelete allows all deletions, the finalizer takes care of the cleanup.
+func (v *DashboardValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *DashboardValidator) validate(ctx context.Context, obj runtime.Object) error {
+	dashboard, ok := obj.(*dawgv1.Dashboard)
+	if !ok {
+		return fmt.Errorf("expected a Dashboard, got %T", obj)
+	}
+
+	var (
+		specPath      = field.NewPath("spec")
+		generatorPath = specPath.Child("generator")
+		configPath    = specPath.Child("config")
+		errs          field.ErrorList
+	)
+
+	generatorURL, err := url.Parse(dashboard.Spec.Generator)
+	switch {
+	case dashboard.Spec.Generator == "":
+		errs = append(errs, field.Required(generatorPath, "must reference a generator"))
+	case err != nil:
+		errs = append(errs, field.Invalid(generatorPath, dashboard.Spec.Generator, err.Error()))
+	case !v.supports(generatorURL):
+		errs = append(
+			errs,
+			field.Invalid(generatorPath, dashboard.Spec.Generator, fmt.Sprintf("unsupported scheme %q", generatorURL.Scheme)),
+		)
+	default:
+		if err := v.checkPinned(generatorURL); err != nil {
+			errs = append(errs, field.Invalid(generatorPath, dashboard.Spec.Generator, err.Error()))
+		}
+	}
+
+	if folder := dashboard.Spec.Folder; folder != nil {
+		folderPath := specPath.Child("folder")
+
+		switch {
+		case folder.GrafanaFolder != "" && (folder.UID != "" || folder.Title != "" || folder.Create):
+			errs = append(errs, field.Invalid(folderPath.Child("grafanaFolder"), folder.GrafanaFolder, "excludes uid, title and create"))
+		case folder.GrafanaFolder == "" && folder.UID == "" && folder.Title == "":
+			errs = append(errs, field.Required(folderPath, "must set a uid, a title or a grafanaFolder"))
+		case folder.Create && folder.Title == "":
+			errs = append(errs, field.Required(folderPath.Child("title"), "required to create the folder"))
+		}
+	}
+
+	for i, source := range dashboard.Spec.ConfigFrom {
+		if (source.ConfigMapKeyRef == nil) == (source.SecretKeyRef == nil) {
+			errs = append(errs, field.Invalid(specPath.Child("configFrom").Index(i), field.OmitValueType{}, errInvalidConfigSource.Error()))
+		}
+	}
+
+	if ref := dashboard.Spec.Grafana; ref != nil {
+		grafanaPath := specPath.Child("grafana")
+
+		switch {
+		case (ref.Name == "") == (ref.Selector == nil):
+			errs = append(errs, field.Invalid(grafanaPath, field.OmitValueType{}, "exactly one of name or selector must be set"))
+		case ref.Selector != nil:
+			if _, err := metav1.LabelSelectorAsSelector(ref.Selector); err != nil {
+				errs = append(errs, field.Invalid(grafanaPath.Child("selector"), field.OmitValueType{}, err.Error()))
+			}
+		}
+
+		if dashboard.Spec.Folder != nil && dashboard.Spec.Folder.GrafanaFolder != "" {
+			errs = append(
+				errs,
+				field.Invalid(specPath.Child("folder", "grafanaFolder"), dashboard.Spec.Folder.GrafanaFolder, errGrafanaFolderInstance.Error()),
+			)
+		}
+	}
+
+	var config any
+	if err := yaml.Unmarshal([]byte(dashboard.Spec.Config), &config); err != nil {
+		errs = append(errs, field.Invalid(configPath, field.OmitValueType{}, "must be valid YAML: "+err.Error()))
+	}
+
+	if len(errs) == 0 && v.dryRun {
+		errs = append(errs, v.dryRunGenerator(ctx, generatorURL, dashboard)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(dawgv1.GroupVersion.WithKind("Dashboard").GroupKind(), dashboard.Name, errs)
+}
+
+func (v *DashboardValidator) supports(generatorURL *url.URL) bool {
+	checker, ok := v.generatorStore.(generator.URLChecker)
+	if !ok {
+		return true
+	}
+
+	return checker.Supports(generatorURL)
+}
+
+// dryRunGenerator runs the generator with the dashboard config, and checks that it produces something the controller can apply.
+func (v *DashboardValidator) dryRunGenerator(ctx context.Context, generatorURL *url.URL, dashboard *dawgv1.Dashboard) field.ErrorList {
+	var (
+		generatorPath = field.NewPath("spec", "generator")
+		configPath    = field.NewPath("spec", "config")
+	)
+
+	credentials, err := v.registryCredentials(ctx, v.apiReader, dashboard.Namespace, dashboard.Spec.ImagePullSecrets)
+	if err != nil {
+		return field.ErrorList{
+			field.Invalid(field.NewPath("spec", "imagePullSecrets"), field.OmitValueType{}, err.Error()),
+		}
+	}
+
+	gen, err := v.generatorStore.Load(generator.WithCredentials(ctx, credentials), generatorURL)
+	if err != nil {
+		return field.ErrorList{
+			field.Invalid(generatorPath, dashboard.Spec.Generator, "could not load generator: "+err.Error()),
+		}
+	}
+
+	config, err := generatorConfig(ctx, v.apiReader, dashboard.Namespace, dashboard.Spec.Config, dashboard.Spec.ConfigFrom)
+	if err != nil {
+		return field.ErrorList{
+			field.Invalid(field.NewPath("spec", "configFrom"), field.OmitValueType{}, err.Error()),
+		}
+	}
+
+	// The validator does not talk to Grafana, the generator only gets to know about the dashboard.
+	// Generators relying on the Grafana facts must cope with their absence to pass the dry-run.
+	genCtx := generator.WithExecutionContext(ctx, &gdk.Context{Resource: resourceContext("Dashboard", dashboard)})
+
+	result, err := v.runtime.Execute(genCtx, gen, config)
+	if err != nil {
+		var (
+			configErr *generator.ConfigValidationError
+			gdkErr    *gdk.RuntimeError
+			errs      field.ErrorList
+		)
+
+		switch {
+		case errors.As(err, &configErr):
+			for _, violation := range configErr.Violations {
+				errs = append(errs, field.Invalid(configFieldPath(configPath, violation.Field), field.OmitValueType{}, violation.Message))
+			}
+		case errors.As(err, &gdkErr) && gdkErr.Field != "":
+			errs = append(errs, field.Invalid(configFieldPath(configPath, gdkErr.Field), field.OmitValueType{}, gdkErr.Err))
+		default:
+			errs = append(errs, field.Invalid(configPath, field.OmitValueType{}, "generator dry-run failed: "+err.Error()))
+		}
+
+		return errs
+	}
+
+	if _, _, err := splitResources(result.AllResources()); err != nil {
+		return field.ErrorList{
+			field.Invalid(generatorPath, dashboard.Spec.Generator, "generator produced an invalid output: "+err.Error()),
+		}
+	}
+
+	return nil
+}
+
+// configFieldPath points to a field within the config, the field path is only a hint as the config is an opaque string.
+func configFieldPath(configPath *field.Path, configField string) *field.Path {
+	if configField == "" {
+		return configPath
+	}
+
+	return configPath.Key(configField)
+}
+
+// SetupWebhookWithManager registers the validating webhook with the Manager.
+func (v *DashboardValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	v.apiReader = mgr.GetAPIReader()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&dawgv1.Dashboard{}).
+		WithValidator(v).
+		Complete()
+}
@@ -0,0 +1,303 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDashboardValidator_RejectsInvalidDashboards(t *testing.T) {
+	genStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	validator := controller.NewDashboardValidator(genStore, nil, false)
+
+	for _, testCase := range []struct {
+		desc      string
+		spec      dawgv1.DashboardSpec
+		wantField string
+	}{
+		{
+			desc: "valid",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+			},
+		},
+		{
+			desc: "missing generator",
+			spec: dawgv1.DashboardSpec{
+				Config: "some: config",
+			},
+			wantField: "spec.generator",
+		},
+		{
+			desc: "unparseable generator URL",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost:port/generators/test:v0.0.1",
+				Config:    "some: config",
+			},
+			wantField: "spec.generator",
+		},
+		{
+			desc: "unsupported generator scheme",
+			spec: dawgv1.DashboardSpec{
+				Generator: "ftp://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+			},
+			wantField: "spec.generator",
+		},
+		{
+			desc: "folder without uid nor title",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+				Folder:    &dawgv1.FolderReference{Create: true},
+			},
+			wantField: "spec.folder",
+		},
+		{
+			desc: "folder to create without title",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+				Folder:    &dawgv1.FolderReference{UID: "folder-uid", Create: true},
+			},
+			wantField: "spec.folder.title",
+		},
+		{
+			desc: "grafanaFolder with a uid",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+				Folder:    &dawgv1.FolderReference{GrafanaFolder: "team-folder", UID: "folder-uid"},
+			},
+			wantField: "spec.folder.grafanaFolder",
+		},
+		{
+			desc: "grafana with a name and a selector",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+				Grafana: &dawgv1.GrafanaInstanceReference{
+					Name:     "prod",
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				},
+			},
+			wantField: "spec.grafana",
+		},
+		{
+			desc: "grafanaFolder with grafana instances",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: config",
+				Folder:    &dawgv1.FolderReference{GrafanaFolder: "team-folder"},
+				Grafana:   &dawgv1.GrafanaInstanceReference{Name: "prod"},
+			},
+			wantField: "spec.folder.grafanaFolder",
+		},
+		{
+			desc: "config source without a reference",
+			spec: dawgv1.DashboardSpec{
+				Generator:  "registry://registry.localhost/generators/test:v0.0.1",
+				Config:     "some: config",
+				ConfigFrom: []dawgv1.ConfigSource{{}},
+			},
+			wantField: "spec.configFrom[0]",
+		},
+		{
+			desc: "invalid YAML config",
+			spec: dawgv1.DashboardSpec{
+				Generator: "registry://registry.localhost/generators/test:v0.0.1",
+				Config:    "some: [config",
+			},
+			wantField: "spec.config",
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			dashboard := dawgv1.Dashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-dashboard", Namespace: "default"},
+				Spec:       testCase.spec,
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), &dashboard)
+			assertInvalidField(t, err, testCase.wantField)
+
+			_, err = validator.ValidateUpdate(context.Background(), &dawgv1.Dashboard{}, &dashboard)
+			assertInvalidField(t, err, testCase.wantField)
+		})
+	}
+}
+
+func TestDashboardValidator_RequirePinnedGenerators(t *testing.T) {
+	genStore, err := generator.DefaultStore()
+	require.NoError(t, err)
+
+	validator := controller.NewDashboardValidator(genStore, nil, false, controller.WithRequirePinnedGenerators())
+
+	for _, testCase := range []struct {
+		desc      string
+		generator string
+		wantField string
+	}{
+		{
+			desc:      "pinned by digest",
+			generator: "registry://registry.localhost/generators/test@sha256:62126095f1f8aa90e5c8d9038d5f6801ad3db3b48a2b8e56f848c3c302f78998",
+		},
+		{
+			desc:      "mutable tag",
+			generator: "registry://registry.localhost/generators/test:v0.0.1",
+			wantField: "spec.generator",
+		},
+		{
+			desc:      "file",
+			generator: "file:///generators/test.wasm",
+			wantField: "spec.generator",
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			dashboard := dawgv1.Dashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-dashboard", Namespace: "default"},
+				Spec: dawgv1.DashboardSpec{
+					Generator: testCase.generator,
+					Config:    "some: config",
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), &dashboard)
+			assertInvalidField(t, err, testCase.wantField)
+		})
+	}
+}
+
+func TestDashboardValidator_AdmitsUpdatesLeavingTheSpecUntouched(t *testing.T) {
+	// The dashboard was created before pinned generators were required, and its generator cannot be loaded anymore.
+	validator := controller.NewDashboardValidator(fakeStore{}, nil, true, controller.WithRequirePinnedGenerators())
+
+	oldDashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-dashboard",
+			Namespace:  "default",
+			Finalizers: []string{"dashboard.dawg.urcloud.cc/finalizer"},
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+		},
+	}
+
+	// The controller removes its finalizer.
+	newDashboard := oldDashboard.DeepCopy()
+	newDashboard.Finalizers = nil
+
+	_, err := validator.ValidateUpdate(context.Background(), &oldDashboard, newDashboard)
+	require.NoError(t, err)
+
+	// The dashboard is being deleted.
+	now := metav1.Now()
+	newDashboard.DeletionTimestamp = &now
+	newDashboard.Spec.Config = "some: [config"
+
+	_, err = validator.ValidateUpdate(context.Background(), &oldDashboard, newDashboard)
+	require.NoError(t, err)
+
+	// Spec changes are still validated.
+	newDashboard = oldDashboard.DeepCopy()
+	newDashboard.Spec.Config = "some: other"
+
+	_, err = validator.ValidateUpdate(context.Background(), &oldDashboard, newDashboard)
+	assertInvalidField(t, err, "spec.generator")
+}
+
+func TestDashboardValidator_DryRun(t *testing.T) {
+	ctx := context.Background()
+
+	runtime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	validator := controller.NewDashboardValidator(
+		fakeStore{
+			"fake://foo/bar/biz:v1": {
+				Bin: v1Bin,
+			},
+			"fake://foo/bar/biz:schema": {
+				Bin: v1Bin,
+				Manifest: &generator.Manifest{
+					Name:         "schema",
+					ConfigSchema: []byte(`{"type":"object","properties":{"some":{"type":"integer"}}}`),
+				},
+			},
+		},
+		runtime,
+		true,
+	)
+
+	for _, testCase := range []struct {
+		desc      string
+		spec      dawgv1.DashboardSpec
+		wantField string
+	}{
+		{
+			desc: "valid",
+			spec: dawgv1.DashboardSpec{
+				Generator: "fake://foo/bar/biz:v1",
+				Config:    "some: config",
+			},
+		},
+		{
+			desc: "unknown generator",
+			spec: dawgv1.DashboardSpec{
+				Generator: "fake://foo/bar/biz:unknown",
+				Config:    "some: config",
+			},
+			wantField: "spec.generator",
+		},
+		{
+			desc: "config does not match the generator schema",
+			spec: dawgv1.DashboardSpec{
+				Generator: "fake://foo/bar/biz:schema",
+				Config:    "some: config",
+			},
+			wantField: "spec.config[some]",
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			dashboard := dawgv1.Dashboard{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-dashboard", Namespace: "default"},
+				Spec:       testCase.spec,
+			}
+
+			_, err := validator.ValidateCreate(ctx, &dashboard)
+			assertInvalidField(t, err, testCase.wantField)
+		})
+	}
+}
+
+func assertInvalidField(t *testing.T, err error, wantField string) {
+	t.Helper()
+
+	if wantField == "" {
+		require.NoError(t, err)
+		return
+	}
+
+	require.Error(t, err)
+	assert.True(t, apierrors.IsInvalid(err))
+
+	statusErr, ok := err.(*apierrors.StatusError)
+	require.True(t, ok)
+	require.NotNil(t, statusErr.ErrStatus.Details)
+	require.Len(t, statusErr.ErrStatus.Details.Causes, 1)
+	assert.Equal(t, wantField, statusErr.ErrStatus.Details.Causes[0].Field)
+}
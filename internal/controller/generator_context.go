@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+// resourceContext describes the resource a generator runs for.
+func resourceContext(kind string, obj client.Object) *gdk.ResourceContext {
+	return &gdk.ResourceContext{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Labels:    obj.GetLabels(),
+	}
+}
+
+// dashboardContext returns the context of the generator of a dashboard, running for the given Grafana servers.
+// When the Grafana context is enabled, the generator runs for a single server, which the context describes.
+func (r *DashboardReconciler) dashboardContext(ctx context.Context, dashboard *dawgv1.Dashboard, targets []grafanaTarget) (*gdk.Context, error) {
+	genCtx := gdk.Context{Resource: resourceContext("Dashboard", dashboard)}
+
+	if !r.grafanaContext || len(targets) == 0 {
+		return &genCtx, nil
+	}
+
+	grafanaCtx, err := grafanaContext(ctx, targets[0].client)
+	if err != nil {
+		return nil, err
+	}
+
+	genCtx.Grafana = grafanaCtx
+
+	return &genCtx, nil
+}
+
+// grafanaContext describes the version and the datasources of a Grafana server.
+func grafanaContext(ctx context.Context, cl *grafana.Client) (*gdk.GrafanaContext, error) {
+	health, err := cl.Health(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the Grafana version: %w", err)
+	}
+
+	datasources, err := cl.ListDatasources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not list the Grafana datasources: %w", err)
+	}
+
+	grafanaCtx := gdk.GrafanaContext{
+		Version:     health.Version,
+		Datasources: make([]gdk.DatasourceContext, 0, len(datasources)),
+	}
+
+	for _, ds := range datasources {
+		grafanaCtx.Datasources = append(grafanaCtx.Datasources, gdk.DatasourceContext{
+			UID:       ds.UID,
+			Name:      ds.Name,
+			Type:      ds.Type,
+			IsDefault: ds.IsDefault,
+		})
+	}
+
+	return &grafanaCtx, nil
+}
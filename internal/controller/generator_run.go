@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+)
+
+// generatorRun is a generator execution for a resource other than a dashboard.
+type generatorRun struct {
+	// resource is the resource the generator runs for, given to the generator in its context.
+	resource    *gdk.ResourceContext
+	generator   string
+	config      string
+	configFrom  []dawgv1.ConfigSource
+	pullSecrets []corev1.LocalObjectReference
+}
+
+func payloadSourceRun(resource *gdk.ResourceContext, source dawgv1.PayloadSource) generatorRun {
+	return generatorRun{
+		resource:    resource,
+		generator:   source.Generator,
+		config:      source.Config,
+		configFrom:  source.ConfigFrom,
+		pullSecrets: source.ImagePullSecrets,
+	}
+}
+
+// generatorRunError reports why a generator run failed, as a condition reason and message.
+type generatorRunError struct {
+	reason  string
+	message string
+	// retry tells if running the generator again may succeed without changing the resource.
+	retry bool
+	err   error
+}
+
+func (e *generatorRunError) Error() string {
+	return e.message + ": " + e.err.Error()
+}
+
+func (e *generatorRunError) Unwrap() error {
+	return e.err
+}
+
+// runGenerator loads a generator and executes it with its config.
+// It returns the execution result and the digest the generator reference resolved to.
+func (o options) runGenerator(ctx context.Context, reader client.Reader, store generator.Reader, runtime generator.Runtime, run generatorRun, logger logr.Logger) (*generator.ExecutionResult, string, *generatorRunError) {
+	generatorURL, err := url.Parse(run.generator)
+	if err != nil {
+		return nil, "", &generatorRunError{reason: reasonInvalidReference, message: "Could not parse generator refererence as an URL", err: err}
+	}
+
+	if err := o.checkPinned(generatorURL); err != nil {
+		return nil, "", &generatorRunError{reason: reasonNotPinned, message: "Generator reference is refused by the pinning policy", err: err}
+	}
+
+	credentials, err := o.registryCredentials(ctx, reader, run.resource.Namespace, run.pullSecrets)
+	if err != nil {
+		return nil, "", &generatorRunError{reason: reasonCredentialsFailed, message: "Could not resolve registry credentials", retry: true, err: err}
+	}
+
+	gen, err := store.Load(generator.WithCredentials(ctx, credentials), generatorURL)
+	if err != nil {
+		reason, message := classifyLoadError(err)
+		return nil, "", &generatorRunError{reason: reason, message: message, retry: true, err: err}
+	}
+
+	digest := gen.ResolvedDigest.String()
+
+	config, err := generatorConfig(ctx, reader, run.resource.Namespace, run.config, run.configFrom)
+	if err != nil {
+		return nil, digest, &generatorRunError{reason: reasonConfigFailed, message: "Could not resolve the generator config", retry: true, err: err}
+	}
+
+	result, err := runtime.Execute(generator.WithExecutionContext(ctx, &gdk.Context{Resource: run.resource}), gen, config)
+	if err != nil {
+		logExecutionErrorOutput(logger, err)
+
+		failure := classifyExecutionError(err)
+
+		return nil, digest, &generatorRunError{reason: failure.reason, message: failure.message, retry: failure.retry, err: err}
+	}
+
+	logGeneratorOutput(logger, result.Logs, result.Stdout, result.Stderr)
+
+	return result, digest, nil
+}
+
+var errInvalidGeneratorOutput = errors.New("generator must output a single resource")
+
+// singleResourceOutput returns the resource produced by a generator, either as its main payload or as the only resource of its envelope.
+func singleResourceOutput(result *generator.ExecutionResult, kind string) (json.RawMessage, error) {
+	switch {
+	case len(result.Payload) > 0 && len(result.Resources) == 0:
+		return result.Payload, nil
+	case len(result.Payload) == 0 && len(result.Resources) == 1 && result.Resources[0].Kind == kind:
+		return result.Resources[0].Payload, nil
+	default:
+		return nil, fmt.Errorf("%w of kind %q", errInvalidGeneratorOutput, kind)
+	}
+}
+
+// sourcePayload returns the inline payload of a resource, or the resource of the given kind produced by its generator.
+func (o options) sourcePayload(ctx context.Context, reader client.Reader, store generator.Reader, runtime generator.Runtime, resource *gdk.ResourceContext, source dawgv1.PayloadSource, kind string, logger logr.Logger) (json.RawMessage, string, *generatorRunError) {
+	if source.Payload != nil {
+		return source.Payload.Raw, "", nil
+	}
+
+	result, digest, runErr := o.runGenerator(ctx, reader, store, runtime, payloadSourceRun(resource, source), logger)
+	if runErr != nil {
+		return nil, digest, runErr
+	}
+
+	payload, err := singleResourceOutput(result, kind)
+	if err != nil {
+		// The output won't change until the generator or its config does.
+		return nil, digest, &generatorRunError{reason: reasonInvalidOutput, message: "Generator output is invalid", err: err}
+	}
+
+	return payload, digest, nil
+}
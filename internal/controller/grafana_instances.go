@@ -0,0 +1,374 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+var errNoDefaultGrafana = errors.New("the controller has no Grafana configured, a GrafanaInstance must be referenced")
+
+// grafanaTarget is a Grafana server a dashboard is applied to.
+type grafanaTarget struct {
+	// instance is the name of the GrafanaInstance, empty for the Grafana of the controller.
+	instance string
+	client   *grafana.Client
+}
+
+// grafanaTargets returns the Grafana servers a dashboard is applied to, sorted by instance name.
+func (r *DashboardReconciler) grafanaTargets(ctx context.Context, dashboard *dawgv1.Dashboard) ([]grafanaTarget, error) {
+	ref := dashboard.Spec.Grafana
+	if ref == nil {
+		if r.grafana == nil {
+			return nil, errNoDefaultGrafana
+		}
+
+		return []grafanaTarget{{client: r.grafana}}, nil
+	}
+
+	var instances []dawgv1.GrafanaInstance
+
+	if ref.Name != "" {
+		var instance dawgv1.GrafanaInstance
+
+		key := types.NamespacedName{Namespace: dashboard.Namespace, Name: ref.Name}
+		if err := r.k8sClient.Get(ctx, key, &instance); err != nil {
+			return nil, fmt.Errorf("could not get GrafanaInstance %s: %w", key, err)
+		}
+
+		instances = append(instances, instance)
+	} else {
+		selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GrafanaInstance selector: %w", err)
+		}
+
+		var list dawgv1.GrafanaInstanceList
+		if err := r.k8sClient.List(
+			ctx,
+			&list,
+			client.InNamespace(dashboard.Namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+		); err != nil {
+			return nil, fmt.Errorf("could not list GrafanaInstances: %w", err)
+		}
+
+		instances = list.Items
+	}
+
+	targets := make([]grafanaTarget, 0, len(instances))
+
+	for i := range instances {
+		cl, err := r.instances.client(ctx, r.apiReader, &instances[i])
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, grafanaTarget{instance: instances[i].Name, client: cl})
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].instance < targets[j].instance })
+
+	return targets, nil
+}
+
+// instanceTarget returns the Grafana server of a GrafanaInstance a dashboard was applied to.
+// It returns false if the instance does not exist anymore.
+func (r *DashboardReconciler) instanceTarget(ctx context.Context, key types.NamespacedName) (grafanaTarget, bool, error) {
+	if key.Name == "" {
+		return grafanaTarget{client: r.grafana}, r.grafana != nil, nil
+	}
+
+	var instance dawgv1.GrafanaInstance
+
+	if err := r.k8sClient.Get(ctx, key, &instance); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			r.instances.evict(key)
+			return grafanaTarget{}, false, nil
+		}
+
+		return grafanaTarget{}, false, fmt.Errorf("could not get GrafanaInstance %s: %w", key, err)
+	}
+
+	cl, err := r.instances.client(ctx, r.apiReader, &instance)
+	if err != nil {
+		return grafanaTarget{}, false, err
+	}
+
+	return grafanaTarget{instance: instance.Name, client: cl}, true, nil
+}
+
+// instanceClient returns the client of a GrafanaInstance, or the client of the Grafana of the controller if the key has no name.
+func instanceClient(ctx context.Context, reader, apiReader client.Reader, pool *grafanaPool, defaultClient *grafana.Client, key types.NamespacedName) (*grafana.Client, error) {
+	if key.Name == "" {
+		if defaultClient == nil {
+			return nil, errNoDefaultGrafana
+		}
+
+		return defaultClient, nil
+	}
+
+	var instance dawgv1.GrafanaInstance
+
+	if err := reader.Get(ctx, key, &instance); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			pool.evict(key)
+		}
+
+		return nil, fmt.Errorf("could not get GrafanaInstance %s: %w", key, err)
+	}
+
+	return pool.client(ctx, apiReader, &instance)
+}
+
+// grafanaServer identifies the Grafana server and organization a GrafanaInstance points at,
+// or the Grafana of the controller if the key has no name. Instances pointing at the same server share its resources.
+func grafanaServer(ctx context.Context, reader client.Reader, defaultClient *grafana.Client, key types.NamespacedName) (string, error) {
+	if key.Name == "" {
+		if defaultClient == nil {
+			return "", errNoDefaultGrafana
+		}
+
+		return serverKey(defaultClient.Host(), defaultClient.OrgID()), nil
+	}
+
+	var instance dawgv1.GrafanaInstance
+
+	if err := reader.Get(ctx, key, &instance); err != nil {
+		return "", fmt.Errorf("could not get GrafanaInstance %s: %w", key, err)
+	}
+
+	return serverKey(instance.Spec.URL, instance.Spec.OrgID), nil
+}
+
+// serverKey normalizes the URL of a Grafana server, so that the different spellings of an URL give the same key.
+func serverKey(rawURL string, orgID int64) string {
+	org := "#org=" + strconv.FormatInt(orgID, 10)
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL + org
+	}
+
+	var (
+		scheme = strings.ToLower(u.Scheme)
+		host   = strings.ToLower(u.Hostname())
+	)
+
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	}
+
+	return scheme + "://" + host + strings.TrimRight(u.EscapedPath(), "/") + org
+}
+
+// grafanaPool keeps a client per GrafanaInstance, rebuilt when the instance URL, org or token changes.
+type grafanaPool struct {
+	clientOpts []grafana.ClientOpt
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]pooledClient
+}
+
+type pooledClient struct {
+	fingerprint string
+	client      *grafana.Client
+}
+
+func newGrafanaPool(clientOpts []grafana.ClientOpt) *grafanaPool {
+	return &grafanaPool{
+		clientOpts: clientOpts,
+		clients:    make(map[types.NamespacedName]pooledClient),
+	}
+}
+
+func (p *grafanaPool) client(ctx context.Context, reader client.Reader, instance *dawgv1.GrafanaInstance) (*grafana.Client, error) {
+	key := client.ObjectKeyFromObject(instance)
+
+	token, err := instanceToken(ctx, reader, instance)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the token of GrafanaInstance %s: %w", key, err)
+	}
+
+	fingerprint := instanceFingerprint(instance, token)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pooled, ok := p.clients[key]; ok && pooled.fingerprint == fingerprint {
+		return pooled.client, nil
+	}
+
+	opts := append([]grafana.ClientOpt{}, p.clientOpts...)
+	opts = append(opts, grafana.WithAuthToken(token))
+
+	if instance.Spec.OrgID != 0 {
+		opts = append(opts, grafana.WithOrgID(instance.Spec.OrgID))
+	}
+
+	cl := grafana.NewClient(instance.Spec.URL, opts...)
+
+	p.clients[key] = pooledClient{fingerprint: fingerprint, client: cl}
+
+	return cl, nil
+}
+
+func (p *grafanaPool) evict(key types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.clients, key)
+}
+
+func instanceToken(ctx context.Context, reader client.Reader, instance *dawgv1.GrafanaInstance) (string, error) {
+	var (
+		ref    = instance.Spec.TokenSecretRef
+		secret corev1.Secret
+	)
+
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: ref.Name}, &secret); err != nil {
+		return "", err
+	}
+
+	token, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %q has no key %q", ref.Name, ref.Key)
+	}
+
+	return string(token), nil
+}
+
+// instanceFingerprint identifies the configuration of a client, without keeping the token around.
+func instanceFingerprint(instance *dawgv1.GrafanaInstance, token string) string {
+	hash := sha256.New()
+
+	for _, value := range []string{instance.Spec.URL, strconv.FormatInt(instance.Spec.OrgID, 10), token} {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// targetStatus returns the state of a dashboard in a Grafana server.
+// The state in the Grafana of the controller lives at the top of the dashboard status.
+func targetStatus(dashboard *dawgv1.Dashboard, instance string) dawgv1.DashboardInstanceStatus {
+	if instance == "" {
+		return dawgv1.DashboardInstanceStatus{
+			Grafana:         dashboard.Status.Grafana,
+			Resources:       dashboard.Status.Resources,
+			PayloadChecksum: dashboard.Status.PayloadChecksum,
+		}
+	}
+
+	for _, status := range dashboard.Status.Instances {
+		if status.Name == instance {
+			return status
+		}
+	}
+
+	return dawgv1.DashboardInstanceStatus{Name: instance}
+}
+
+func setTargetStatus(dashboard *dawgv1.Dashboard, status dawgv1.DashboardInstanceStatus) {
+	if status.Name == "" {
+		dashboard.Status.Grafana = status.Grafana
+		dashboard.Status.Resources = status.Resources
+		dashboard.Status.PayloadChecksum = status.PayloadChecksum
+		return
+	}
+
+	for i := range dashboard.Status.Instances {
+		if dashboard.Status.Instances[i].Name == status.Name {
+			dashboard.Status.Instances[i] = status
+			return
+		}
+	}
+
+	dashboard.Status.Instances = append(dashboard.Status.Instances, status)
+}
+
+func removeTargetStatus(dashboard *dawgv1.Dashboard, instance string) {
+	if instance == "" {
+		setTargetStatus(dashboard, dawgv1.DashboardInstanceStatus{})
+		return
+	}
+
+	for i := range dashboard.Status.Instances {
+		if dashboard.Status.Instances[i].Name == instance {
+			dashboard.Status.Instances = append(dashboard.Status.Instances[:i], dashboard.Status.Instances[i+1:]...)
+			return
+		}
+	}
+}
+
+// appliedStatuses returns the states of a dashboard in all the Grafana servers it has been applied to.
+func appliedStatuses(dashboard *dawgv1.Dashboard) []dawgv1.DashboardInstanceStatus {
+	var statuses []dawgv1.DashboardInstanceStatus
+
+	if dashboard.Status.Grafana.UID != "" || len(dashboard.Status.Resources) > 0 {
+		statuses = append(statuses, targetStatus(dashboard, ""))
+	}
+
+	return append(statuses, dashboard.Status.Instances...)
+}
+
+// instanceDashboards enqueues the dashboards of the namespace of a GrafanaInstance that reference it.
+func (r *DashboardReconciler) instanceDashboards(ctx context.Context, obj client.Object) []reconcile.Request {
+	var dashboards dawgv1.DashboardList
+
+	if err := r.k8sClient.List(ctx, &dashboards, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the dashboards referencing a GrafanaInstance")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, dashboard := range dashboards.Items {
+		if referencesInstance(&dashboard, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dashboard)})
+		}
+	}
+
+	return requests
+}
+
+// referencesInstance tells if a dashboard targets an instance, or has been applied to it.
+func referencesInstance(dashboard *dawgv1.Dashboard, instance client.Object) bool {
+	for _, status := range dashboard.Status.Instances {
+		if status.Name == instance.GetName() {
+			return true
+		}
+	}
+
+	ref := dashboard.Spec.Grafana
+
+	switch {
+	case ref == nil:
+		return false
+	case ref.Name != "":
+		return ref.Name == instance.GetName()
+	default:
+		selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+		return err == nil && selector.Matches(labels.Set(instance.GetLabels()))
+	}
+}
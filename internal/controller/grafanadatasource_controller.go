@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const datasourceFinalizer = "grafanadatasource.dawg.urcloud.cc/finalizer"
+
+// GrafanaDatasourceReconciler reconciles a GrafanaDatasource object
+type GrafanaDatasourceReconciler struct {
+	statusReporter
+
+	// apiReader reads secrets straight from the API server, to avoid caching all the secrets of the cluster.
+	apiReader client.Reader
+	// grafana is the Grafana of the controller, nil if datasources must reference a GrafanaInstance.
+	grafana   *grafana.Client
+	instances *grafanaPool
+}
+
+func NewGrafanaDatasourceReconciler(grafana *grafana.Client, opts ...Option) *GrafanaDatasourceReconciler {
+	options := newOptions(opts)
+
+	return &GrafanaDatasourceReconciler{
+		grafana:   grafana,
+		instances: newGrafanaPool(options.grafanaClientOpts),
+	}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanadatasources,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanadatasources/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanadatasources/finalizers,verbs=update
+
+// Reconcile handles datasource reconciliation.
+func (r *GrafanaDatasourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var datasource dawgv1.GrafanaDatasource
+
+	if err := r.k8sClient.Get(ctx, req.NamespacedName, &datasource); err != nil {
+		logger.Error(err, "Could not fetch the datasource")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !datasource.DeletionTimestamp.IsZero() {
+		return r.deleteDatasource(ctx, &datasource, logger)
+	}
+
+	return r.applyDatasource(ctx, &datasource, logger)
+}
+
+func (r *GrafanaDatasourceReconciler) applyDatasource(ctx context.Context, datasource *dawgv1.GrafanaDatasource, logger logr.Logger) (ctrl.Result, error) {
+	uid := datasourceUID(datasource)
+	logger = logger.WithValues("uid", uid, "grafana_instance", datasource.Spec.GrafanaInstance)
+
+	logger.Info("Applying datasource")
+
+	if !controllerutil.ContainsFinalizer(datasource, datasourceFinalizer) {
+		controllerutil.AddFinalizer(datasource, datasourceFinalizer)
+		if err := r.k8sClient.Update(ctx, datasource); err != nil {
+			r.setFailureStatus(ctx, datasource, reasonFinalizerFailed, "Could set finalizer", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	secureJSONData, err := r.secureJSONData(ctx, datasource)
+	if err != nil {
+		r.setFailureStatus(ctx, datasource, reasonCredentialsFailed, "Could not read the secure settings of the datasource", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: datasource.Namespace, Name: datasource.Spec.GrafanaInstance},
+	)
+	if err != nil {
+		r.setFailureStatus(ctx, datasource, reasonInstancesFailed, "Could not resolve the Grafana instance of the datasource", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	// The datasource is applied to another instance, the previous one must go.
+	if previous := datasource.Status; previous.UID != "" && previous.GrafanaInstance != datasource.Spec.GrafanaInstance {
+		if err := r.deleteFromGrafana(ctx, datasource); err != nil {
+			r.setFailureStatus(ctx, datasource, reasonGrafanaFailed, "Could not delete the datasource from the previous Grafana instance", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	req := grafana.DatasourceRequest{
+		Datasource: grafana.Datasource{
+			UID:           uid,
+			Name:          datasource.Spec.Name,
+			Type:          datasource.Spec.Type,
+			Access:        datasource.Spec.Access,
+			URL:           datasource.Spec.URL,
+			Database:      datasource.Spec.Database,
+			User:          datasource.Spec.User,
+			BasicAuth:     datasource.Spec.BasicAuth,
+			BasicAuthUser: datasource.Spec.BasicAuthUser,
+			IsDefault:     datasource.Spec.IsDefault,
+		},
+		SecureJSONData: secureJSONData,
+	}
+
+	if datasource.Spec.JSONData != nil {
+		req.JSONData = json.RawMessage(datasource.Spec.JSONData.Raw)
+	}
+
+	grafanaDatasource, err := cl.UpdateDatasource(ctx, &req)
+	if grafana.IsNotFound(err) {
+		grafanaDatasource, err = cl.CreateDatasource(ctx, &req)
+	}
+
+	if err != nil {
+		r.setFailureStatus(ctx, datasource, reasonGrafanaFailed, "Could not create or update the datasource in Grafana", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	datasource.Status.UID = uid
+	datasource.Status.ID = grafanaDatasource.ID
+	datasource.Status.GrafanaInstance = datasource.Spec.GrafanaInstance
+
+	r.setStatus(ctx, datasource, metav1.ConditionTrue, reasonReady, "Datasource is ready", logger)
+
+	logger.Info("Applied datasource")
+
+	return ctrl.Result{}, nil
+}
+
+// secureJSONData reads the secret settings of a datasource from their Secrets.
+func (r *GrafanaDatasourceReconciler) secureJSONData(ctx context.Context, datasource *dawgv1.GrafanaDatasource) (map[string]string, error) {
+	if len(datasource.Spec.SecureJSONData) == 0 {
+		return nil, nil
+	}
+
+	data := make(map[string]string, len(datasource.Spec.SecureJSONData))
+
+	for _, field := range datasource.Spec.SecureJSONData {
+		var (
+			ref    = field.SecretKeyRef
+			secret corev1.Secret
+		)
+
+		if err := r.apiReader.Get(ctx, types.NamespacedName{Namespace: datasource.Namespace, Name: ref.Name}, &secret); err != nil {
+			if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+				continue
+			}
+
+			return nil, fmt.Errorf("secureJsonData %q: could not get Secret %q: %w", field.Name, ref.Name, err)
+		}
+
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			if isOptional(ref.Optional) {
+				continue
+			}
+
+			return nil, fmt.Errorf("secureJsonData %q: Secret %q has no key %q", field.Name, ref.Name, ref.Key)
+		}
+
+		data[field.Name] = string(value)
+	}
+
+	return data, nil
+}
+
+func (r *GrafanaDatasourceReconciler) deleteDatasource(ctx context.Context, datasource *dawgv1.GrafanaDatasource, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(datasource, datasourceFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if datasource.Status.UID == "" {
+		logger.Info("Deleting a datasource never applied, not deleting the Grafana datasource")
+	} else {
+		logger.Info("Deleting datasource")
+
+		if err := r.deleteFromGrafana(ctx, datasource); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(datasource, datasourceFinalizer)
+	if err := r.k8sClient.Update(ctx, datasource); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted datasource")
+
+	return ctrl.Result{}, nil
+}
+
+// deleteFromGrafana deletes the applied datasource. There is nothing to delete if its GrafanaInstance is gone.
+func (r *GrafanaDatasourceReconciler) deleteFromGrafana(ctx context.Context, datasource *dawgv1.GrafanaDatasource) error {
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: datasource.Namespace, Name: datasource.Status.GrafanaInstance},
+	)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	err = cl.DeleteDatasource(ctx, &grafana.DeleteDatasourceRequest{UID: datasource.Status.UID})
+	if err != nil && !grafana.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func datasourceUID(datasource *dawgv1.GrafanaDatasource) string {
+	if datasource.Spec.UID != "" {
+		return datasource.Spec.UID
+	}
+
+	return string(datasource.UID)
+}
+
+// secretDatasources enqueues the datasources reading secure settings from a Secret.
+func (r *GrafanaDatasourceReconciler) secretDatasources(ctx context.Context, obj client.Object) []reconcile.Request {
+	var datasources dawgv1.GrafanaDatasourceList
+
+	if err := r.k8sClient.List(ctx, &datasources, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the datasources reading a Secret")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, datasource := range datasources.Items {
+		for _, field := range datasource.Spec.SecureJSONData {
+			if field.SecretKeyRef.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&datasource)})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaDatasourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.k8sClient = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(
+			&dawgv1.GrafanaDatasource{},
+			// Do not process status updates, nor delete events as we're using finalizers.
+			builder.WithPredicates(
+				predicate.GenerationChangedPredicate{},
+				predicate.Funcs{
+					DeleteFunc: func(e event.DeleteEvent) bool { return false },
+				},
+			),
+		).
+		// Secrets are read from the API server, only their metadata is cached to know when they change.
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.secretDatasources),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Complete(r)
+}
@@ -0,0 +1,162 @@
+package controller_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGrafanaDatasourceController_AppliesDatasourceWithSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				// The datasource does not exist yet, the update is answered with a 404.
+				"http://somegrafana.com/api/datasources/uid/prometheus": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusNotFound,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"Data source not found"}`)),
+					}
+				},
+				"http://somegrafana.com/api/datasources": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id":3,"name":"Prometheus","message":"Datasource added","datasource":{"id":3,"uid":"prometheus","name":"Prometheus","type":"prometheus"}}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewGrafanaDatasourceReconciler(grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus-credentials",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{"password": []byte("s3cr3t")},
+	}
+
+	err := k8sClient.Create(ctx, &secret)
+	require.NoError(t, err)
+
+	datasource := dawgv1.GrafanaDatasource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus",
+			Namespace: "default",
+		},
+		Spec: dawgv1.GrafanaDatasourceSpec{
+			UID:           "prometheus",
+			Name:          "Prometheus",
+			Type:          "prometheus",
+			Access:        "proxy",
+			URL:           "http://prometheus:9090",
+			BasicAuth:     true,
+			BasicAuthUser: "grafana",
+			SecureJSONData: []dawgv1.SecureJSONField{
+				{
+					Name: "basicAuthPassword",
+					SecretKeyRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+						Key:                  "password",
+					},
+				},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &datasource)
+	require.NoError(t, err)
+
+	// This should try to update the datasource, then create it.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	updateRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPut, updateRequest.Method)
+
+	createRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodPost, createRequest.Method)
+	assert.JSONEq(
+		t,
+		`{
+			"uid":"prometheus",
+			"name":"Prometheus",
+			"type":"prometheus",
+			"access":"proxy",
+			"url":"http://prometheus:9090",
+			"basicAuth":true,
+			"basicAuthUser":"grafana",
+			"isDefault":false,
+			"secureJsonData":{"basicAuthPassword":"s3cr3t"}
+		}`,
+		readAll(t, grafanaBackend.readRequestBody(t, 1)),
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&datasource), &datasource)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(datasource.Status.Conditions, dawgv1.GrafanaDatasourceConditionReady)
+	})
+
+	assert.Equal(t, "prometheus", datasource.Status.UID)
+	assert.Equal(t, int64(3), datasource.Status.ID)
+
+	// Deleting the secret reconciles the datasource, which cannot read its secure settings anymore.
+	err = k8sClient.Delete(ctx, &secret)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&datasource), &datasource)
+		require.NoError(t, err)
+
+		ready := meta.FindStatusCondition(datasource.Status.Conditions, dawgv1.GrafanaDatasourceConditionReady)
+		return ready != nil && ready.Reason == "CredentialsFailed"
+	})
+
+	err = k8sClient.Delete(ctx, &datasource)
+	require.NoError(t, err)
+
+	// This should delete the datasource.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/datasources/uid/prometheus", deleteRequest.URL.Path)
+}
@@ -0,0 +1,350 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const folderFinalizer = "grafanafolder.dawg.urcloud.cc/finalizer"
+
+// defaultFolderPermissions are the permissions Grafana grants on the folders it creates.
+var defaultFolderPermissions = []grafana.FolderPermission{
+	{Role: "Viewer", Permission: grafana.PermissionView},
+	{Role: "Editor", Permission: grafana.PermissionEdit},
+}
+
+var errNoFolderGrafana = errors.New("GrafanaFolders are applied to the Grafana of the controller, which is not configured with -grafana-url")
+
+var folderPermissionLevels = map[string]int{
+	"View":  grafana.PermissionView,
+	"Edit":  grafana.PermissionEdit,
+	"Admin": grafana.PermissionAdmin,
+}
+
+// GrafanaFolderReconciler reconciles a GrafanaFolder object.
+// Deleting a folder in Grafana deletes the dashboards and alert rules it holds, so a GrafanaFolder is only deleted
+// once no Dashboard nor AlertRuleGroup uses the folder anymore.
+type GrafanaFolderReconciler struct {
+	statusReporter
+
+	// grafana is the Grafana of the controller, folders are not applied without it.
+	grafana *grafana.Client
+}
+
+func NewGrafanaFolderReconciler(grafana *grafana.Client) *GrafanaFolderReconciler {
+	return &GrafanaFolderReconciler{grafana: grafana}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanafolders,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanafolders/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=grafanafolders/finalizers,verbs=update
+
+// Reconcile handles folder reconciliation.
+func (r *GrafanaFolderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var folder dawgv1.GrafanaFolder
+
+	if err := r.k8sClient.Get(ctx, req.NamespacedName, &folder); err != nil {
+		logger.Error(err, "Could not fetch the folder")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !folder.DeletionTimestamp.IsZero() {
+		return r.deleteFolder(ctx, &folder, logger)
+	}
+
+	if r.grafana == nil {
+		r.setFailureStatus(ctx, &folder, reasonNoGrafana, "Could not apply the folder", errNoFolderGrafana, logger)
+		// Nothing changes until the controller is restarted with a Grafana.
+		return ctrl.Result{}, nil
+	}
+
+	return r.applyFolder(ctx, &folder, logger)
+}
+
+func (r *GrafanaFolderReconciler) applyFolder(ctx context.Context, folder *dawgv1.GrafanaFolder, logger logr.Logger) (ctrl.Result, error) {
+	uid := folderUID(folder)
+	logger = logger.WithValues("uid", uid)
+
+	logger.Info("Applying folder")
+
+	if !controllerutil.ContainsFinalizer(folder, folderFinalizer) {
+		controllerutil.AddFinalizer(folder, folderFinalizer)
+		if err := r.k8sClient.Update(ctx, folder); err != nil {
+			r.setFailureStatus(ctx, folder, reasonFinalizerFailed, "Could set finalizer", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	grafanaFolder, err := r.grafana.GetFolder(ctx, &grafana.GetFolderRequest{UID: uid})
+	switch {
+	case grafana.IsNotFound(err):
+		grafanaFolder, err = r.grafana.CreateFolder(ctx, &grafana.CreateFolderRequest{UID: uid, Title: folder.Spec.Title})
+	case err == nil && grafanaFolder.Title != folder.Spec.Title:
+		grafanaFolder, err = r.grafana.UpdateFolder(
+			ctx,
+			&grafana.UpdateFolderRequest{
+				UID:       uid,
+				Title:     folder.Spec.Title,
+				Overwrite: true,
+			},
+		)
+	}
+	if err != nil {
+		r.setFailureStatus(ctx, folder, reasonGrafanaFailed, "Could not create or update the folder in Grafana", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	var permissions []grafana.FolderPermission
+
+	switch {
+	case len(folder.Spec.Permissions) > 0:
+		permissions, err = r.folderPermissions(ctx, folder.Spec.Permissions)
+		if err != nil {
+			r.setFailureStatus(ctx, folder, reasonPermissionsFailed, "Could not resolve the folder permissions", err, logger)
+			return ctrl.Result{}, err
+		}
+	case folder.Status.PermissionsApplied:
+		// The permissions were removed from the spec, give the folder back the permissions Grafana grants by default.
+		permissions = defaultFolderPermissions
+	}
+
+	if permissions != nil {
+		if err := r.grafana.UpdateFolderPermissions(
+			ctx,
+			&grafana.UpdateFolderPermissionsRequest{UID: uid, Items: permissions},
+		); err != nil {
+			r.setFailureStatus(ctx, folder, reasonPermissionsFailed, "Could not update the folder permissions", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	folder.Status.UID = grafanaFolder.UID
+	folder.Status.URL = grafanaFolder.URL
+	folder.Status.PermissionsApplied = len(folder.Spec.Permissions) > 0
+
+	r.setStatus(ctx, folder, metav1.ConditionTrue, reasonReady, "Folder is ready", logger)
+
+	logger.Info("Applied folder")
+
+	return ctrl.Result{}, nil
+}
+
+// folderPermissions resolves the teams and users the permissions are granted to.
+func (r *GrafanaFolderReconciler) folderPermissions(ctx context.Context, permissions []dawgv1.FolderPermission) ([]grafana.FolderPermission, error) {
+	resolved := make([]grafana.FolderPermission, len(permissions))
+
+	for i, permission := range permissions {
+		level, ok := folderPermissionLevels[permission.Permission]
+		if !ok {
+			return nil, fmt.Errorf("unknown permission %q", permission.Permission)
+		}
+
+		resolved[i] = grafana.FolderPermission{Role: permission.Role, Permission: level}
+
+		switch {
+		case permission.Team != "":
+			resp, err := r.grafana.SearchTeams(ctx, &grafana.SearchTeamsRequest{Name: permission.Team})
+			if err != nil {
+				return nil, fmt.Errorf("could not search team %q: %w", permission.Team, err)
+			}
+
+			teamID, ok := findTeam(resp.Teams, permission.Team)
+			if !ok {
+				return nil, fmt.Errorf("team %q not found", permission.Team)
+			}
+
+			resolved[i].TeamID = teamID
+		case permission.User != "":
+			user, err := r.grafana.LookupUser(ctx, &grafana.LookupUserRequest{LoginOrEmail: permission.User})
+			if err != nil {
+				return nil, fmt.Errorf("could not find user %q: %w", permission.User, err)
+			}
+
+			resolved[i].UserID = user.ID
+		}
+	}
+
+	return resolved, nil
+}
+
+func findTeam(teams []grafana.Team, name string) (int, bool) {
+	// The search matches team names partially.
+	for _, team := range teams {
+		if team.Name == name {
+			return team.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+func (r *GrafanaFolderReconciler) deleteFolder(ctx context.Context, folder *dawgv1.GrafanaFolder, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(folder, folderFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// Without a Grafana, the folder was never applied by this controller and there is nothing to delete.
+	if r.grafana == nil {
+		logger.Info("Deleting a folder without a Grafana, not deleting the Grafana folder")
+		return r.removeFinalizer(ctx, folder, logger)
+	}
+
+	users, err := r.folderUsers(ctx, folder)
+	if err != nil {
+		r.setFailureStatus(ctx, folder, reasonInUse, "Could not list the resources using the folder", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	if len(users) > 0 {
+		r.setFailureStatus(
+			ctx,
+			folder,
+			reasonInUse,
+			"Folder is still in use, deleting it would delete its dashboards and alert rules",
+			fmt.Errorf("used by %s", strings.Join(users, ", ")),
+			logger,
+		)
+
+		// The folder is reconciled again when the resources using it change or go away.
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Deleting folder")
+
+	err = r.grafana.DeleteFolder(ctx, &grafana.DeleteFolderRequest{UID: folderUID(folder)})
+	if err != nil && !grafana.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	return r.removeFinalizer(ctx, folder, logger)
+}
+
+func (r *GrafanaFolderReconciler) removeFinalizer(ctx context.Context, folder *dawgv1.GrafanaFolder, logger logr.Logger) (ctrl.Result, error) {
+	controllerutil.RemoveFinalizer(folder, folderFinalizer)
+	if err := r.k8sClient.Update(ctx, folder); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted folder")
+
+	return ctrl.Result{}, nil
+}
+
+// folderUsers lists the Dashboards and AlertRuleGroups referencing a folder, or applied to it in the Grafana of the controller.
+func (r *GrafanaFolderReconciler) folderUsers(ctx context.Context, folder *dawgv1.GrafanaFolder) ([]string, error) {
+	uid := folderUID(folder)
+
+	server, err := grafanaServer(ctx, r.k8sClient, r.grafana, types.NamespacedName{})
+	if err != nil {
+		return nil, err
+	}
+
+	// inFolder tells if a resource of a namespace is applied to the folder, through the given GrafanaInstance.
+	inFolder := func(namespace, instance, resourceFolderUID string) bool {
+		if resourceFolderUID != uid {
+			return false
+		}
+
+		instanceServer, err := grafanaServer(ctx, r.k8sClient, r.grafana, types.NamespacedName{Namespace: namespace, Name: instance})
+
+		return err == nil && instanceServer == server
+	}
+
+	var users []string
+
+	var dashboards dawgv1.DashboardList
+	if err := r.k8sClient.List(ctx, &dashboards); err != nil {
+		return nil, fmt.Errorf("could not list the dashboards: %w", err)
+	}
+
+	for _, dashboard := range dashboards.Items {
+		used := dashboard.Namespace == folder.Namespace &&
+			dashboard.Spec.Folder != nil &&
+			dashboard.Spec.Folder.GrafanaFolder == folder.Name
+
+		used = used || inFolder(dashboard.Namespace, "", dashboard.Status.Grafana.FolderUID)
+
+		for _, instance := range dashboard.Status.Instances {
+			used = used || inFolder(dashboard.Namespace, instance.Name, instance.Grafana.FolderUID)
+		}
+
+		if used {
+			users = append(users, "Dashboard "+client.ObjectKeyFromObject(&dashboard).String())
+		}
+	}
+
+	var groups dawgv1.AlertRuleGroupList
+	if err := r.k8sClient.List(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("could not list the rule groups: %w", err)
+	}
+
+	for _, group := range groups.Items {
+		if inFolder(group.Namespace, group.Status.GrafanaInstance, group.Status.FolderUID) {
+			users = append(users, "AlertRuleGroup "+client.ObjectKeyFromObject(&group).String())
+		}
+	}
+
+	return users, nil
+}
+
+// deletingFolders enqueues the folders waiting for the resources using them to go away.
+func (r *GrafanaFolderReconciler) deletingFolders(ctx context.Context, _ client.Object) []reconcile.Request {
+	var folders dawgv1.GrafanaFolderList
+
+	if err := r.k8sClient.List(ctx, &folders); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the folders")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, folder := range folders.Items {
+		if !folder.DeletionTimestamp.IsZero() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&folder)})
+		}
+	}
+
+	return requests
+}
+
+// folderUID returns the UID of the folder in Grafana, the resource UID is used unless one is given.
+func folderUID(folder *dawgv1.GrafanaFolder) string {
+	if folder.Spec.UID != "" {
+		return folder.Spec.UID
+	}
+
+	return string(folder.UID)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GrafanaFolderReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.k8sClient = mgr.GetClient()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Do not process status updates.
+		For(&dawgv1.GrafanaFolder{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Deleted folders still in use are reconciled again when the resources using them change, their status included.
+		Watches(&dawgv1.Dashboard{}, handler.EnqueueRequestsFromMapFunc(r.deletingFolders)).
+		Watches(&dawgv1.AlertRuleGroup{}, handler.EnqueueRequestsFromMapFunc(r.deletingFolders)).
+		Complete(r)
+}
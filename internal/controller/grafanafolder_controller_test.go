@@ -0,0 +1,326 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGrafanaFolderController_AppliesFolderAndPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/folders/team-folder": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 1, "uid":"team-folder","title":"Team","url":"/dashboards/f/team-folder/team"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/teams/search?name=backend": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"totalCount":2,"teams":[{"id":2,"name":"backend-oncall"},{"id":3,"name":"backend"}]}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/folders/team-folder/permissions": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"Folder permissions updated"}`)),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewGrafanaFolderReconciler(grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	folder := dawgv1.GrafanaFolder{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "team-folder",
+			Namespace: "default",
+		},
+		Spec: dawgv1.GrafanaFolderSpec{
+			Title: "Team",
+			UID:   "team-folder",
+			Permissions: []dawgv1.FolderPermission{
+				{Role: "Viewer", Permission: "View"},
+				{Team: "backend", Permission: "Edit"},
+			},
+		},
+	}
+
+	err := k8sClient.Create(ctx, &folder)
+	require.NoError(t, err)
+
+	// This should get the folder, search the team, then update the permissions.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	folderRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodGet, folderRequest.Method)
+	assert.Equal(t, "/api/folders/team-folder", folderRequest.URL.Path)
+
+	permissionsRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodPost, permissionsRequest.Method)
+
+	var req grafana.UpdateFolderPermissionsRequest
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 2)).Decode(&req)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]grafana.FolderPermission{
+			{Role: "Viewer", Permission: grafana.PermissionView},
+			{TeamID: 3, Permission: grafana.PermissionEdit},
+		},
+		req.Items,
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(
+			ctx,
+			client.ObjectKey{
+				Name:      folder.Name,
+				Namespace: folder.Namespace,
+			},
+			&folder,
+		)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(folder.Status.Conditions, dawgv1.GrafanaFolderConditionReady)
+	})
+
+	assert.Equal(t, "team-folder", folder.Status.UID)
+	assert.Equal(t, "/dashboards/f/team-folder/team", folder.Status.URL)
+	assert.Equal(t, folder.Generation, folder.Status.ObservedGeneration)
+
+	dashboard := dawgv1.Dashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "team-dashboard",
+			Namespace: "default",
+		},
+		Spec: dawgv1.DashboardSpec{
+			Generator: "fake://foo/bar/biz:v1",
+			Config:    "some: config",
+			Folder:    &dawgv1.FolderReference{GrafanaFolder: "team-folder"},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &dashboard)
+	require.NoError(t, err)
+
+	err = k8sClient.Delete(ctx, &folder)
+	require.NoError(t, err)
+
+	// The folder is kept while a dashboard uses it, Grafana would delete the dashboard along with it.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&folder), &folder)
+		require.NoError(t, err)
+
+		cond := meta.FindStatusCondition(folder.Status.Conditions, dawgv1.GrafanaFolderConditionReady)
+		return cond != nil && cond.Reason == "InUse"
+	})
+
+	assert.False(t, grafanaBackend.hasRequest(http.MethodDelete, "http://somegrafana.com/api/folders/team-folder"))
+
+	err = k8sClient.Delete(ctx, &dashboard)
+	require.NoError(t, err)
+
+	// This should delete the folder in Grafana.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 3)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/folders/team-folder", deleteRequest.URL.Path)
+}
+
+func TestGrafanaFolderController_ReportsMissingGrafana(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	var (
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewGrafanaFolderReconciler(nil),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	folder := dawgv1.GrafanaFolder{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "team-folder",
+			Namespace: "default",
+		},
+		Spec: dawgv1.GrafanaFolderSpec{Title: "Team"},
+	}
+
+	// The folder was applied before the controller lost its Grafana.
+	folder.Finalizers = []string{"grafanafolder.dawg.urcloud.cc/finalizer"}
+
+	err := k8sClient.Create(ctx, &folder)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&folder), &folder)
+		require.NoError(t, err)
+
+		cond := meta.FindStatusCondition(folder.Status.Conditions, dawgv1.GrafanaFolderConditionReady)
+		return cond != nil && cond.Reason == "NoGrafana"
+	})
+
+	// The finalizer is removed, as there is no Grafana to delete the folder from.
+	err = k8sClient.Delete(ctx, &folder)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&folder), &folder)
+		return apierrors.IsNotFound(err)
+	})
+}
+
+func TestGrafanaFolderController_ResetsRemovedPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/folders/team-folder": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"id": 1, "uid":"team-folder","title":"Team","url":"/dashboards/f/team-folder/team"}`,
+							),
+						),
+					}
+				},
+				"http://somegrafana.com/api/folders/team-folder/permissions": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"Folder permissions updated"}`)),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewGrafanaFolderReconciler(grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	folder := dawgv1.GrafanaFolder{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "team-folder",
+			Namespace: "default",
+		},
+		Spec: dawgv1.GrafanaFolderSpec{
+			Title:       "Team",
+			UID:         "team-folder",
+			Permissions: []dawgv1.FolderPermission{{Role: "Viewer", Permission: "Admin"}},
+		},
+	}
+
+	err := k8sClient.Create(ctx, &folder)
+	require.NoError(t, err)
+
+	// This should get the folder, then update the permissions.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&folder), &folder)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(folder.Status.Conditions, dawgv1.GrafanaFolderConditionReady)
+	})
+
+	assert.True(t, folder.Status.PermissionsApplied)
+
+	// Removing the permissions resets the permissions of the folder.
+	folder.Spec.Permissions = nil
+
+	err = k8sClient.Update(ctx, &folder)
+	require.NoError(t, err)
+
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	permissionsRequest := grafanaBackend.readRequest(t, 3)
+	assert.Equal(t, http.MethodPost, permissionsRequest.Method)
+	assert.Equal(t, "/api/folders/team-folder/permissions", permissionsRequest.URL.Path)
+
+	var req grafana.UpdateFolderPermissionsRequest
+	err = json.NewDecoder(grafanaBackend.readRequestBody(t, 3)).Decode(&req)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]grafana.FolderPermission{
+			{Role: "Viewer", Permission: grafana.PermissionView},
+			{Role: "Editor", Permission: grafana.PermissionEdit},
+		},
+		req.Items,
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&folder), &folder)
+		require.NoError(t, err)
+		return folder.Status.ObservedGeneration == folder.Generation && !folder.Status.PermissionsApplied
+	})
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const libraryPanelFinalizer = "librarypanel.dawg.urcloud.cc/finalizer"
+
+var errInvalidLibraryPanel = errors.New("library panel must have a name and a model")
+
+// LibraryPanelReconciler reconciles a LibraryPanel object
+type LibraryPanelReconciler struct {
+	uidResourceReconciler[*dawgv1.LibraryPanel, *grafana.CreateLibraryElementRequest]
+}
+
+func NewLibraryPanelReconciler(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client, opts ...Option) *LibraryPanelReconciler {
+	return &LibraryPanelReconciler{
+		uidResourceReconciler: newUIDResourceReconciler(libraryPanelHandler, store, runtime, grafana, opts),
+	}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=librarypanels,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=librarypanels/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=librarypanels/finalizers,verbs=update
+
+// libraryPanelHandler applies library panels the same way dashboards apply the ones they generate.
+// Grafana refuses to delete a library panel still used by dashboards, its deletion is retried until they are gone.
+var libraryPanelHandler = uidResourceHandler[*dawgv1.LibraryPanel, *grafana.CreateLibraryElementRequest]{
+	name:       "library panel",
+	kind:       "LibraryPanel",
+	outputKind: gdk.ResourceKindLibraryPanel,
+	finalizer:  libraryPanelFinalizer,
+	newObject:  func() *dawgv1.LibraryPanel { return &dawgv1.LibraryPanel{} },
+	spec: func(libraryPanel *dawgv1.LibraryPanel) (dawgv1.PayloadSource, string) {
+		return libraryPanel.Spec.PayloadSource, libraryPanel.Spec.GrafanaInstance
+	},
+	status: func(libraryPanel *dawgv1.LibraryPanel) uidResourceStatus {
+		return uidResourceStatus{
+			uid:             &libraryPanel.Status.UID,
+			grafanaInstance: &libraryPanel.Status.GrafanaInstance,
+			generatorDigest: &libraryPanel.Status.GeneratorDigest,
+		}
+	},
+	decode: libraryPanelPayload,
+	apply: func(ctx context.Context, cl *grafana.Client, _ string, payload json.RawMessage) error {
+		_, _, err := applyLibraryPanel(ctx, cl, payload)
+		return err
+	},
+	delete: deleteLibraryPanel,
+	applied: func(libraryPanel *dawgv1.LibraryPanel, desired *grafana.CreateLibraryElementRequest) {
+		libraryPanel.Status.Name = desired.Name
+		libraryPanel.Status.FolderUID = desired.FolderUID
+	},
+}
+
+// libraryPanelPayload validates a library panel payload, and sets its UID if it has none.
+func libraryPanelPayload(payload json.RawMessage, uid string) (json.RawMessage, *grafana.CreateLibraryElementRequest, string, error) {
+	payload, err := withUID(payload, uid)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("could not decode library panel: %w", err)
+	}
+
+	var libraryPanel grafana.CreateLibraryElementRequest
+	if err := json.Unmarshal(payload, &libraryPanel); err != nil {
+		return nil, nil, "", fmt.Errorf("could not decode library panel: %w", err)
+	}
+
+	if libraryPanel.Name == "" || len(libraryPanel.Model) == 0 {
+		return nil, nil, "", errInvalidLibraryPanel
+	}
+
+	return payload, &libraryPanel, libraryPanel.UID, nil
+}
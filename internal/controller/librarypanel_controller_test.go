@@ -0,0 +1,125 @@
+package controller_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestLibraryPanelController_CreatesDeletesInlineLibraryPanel(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				// The library panel does not exist yet.
+				"http://somegrafana.com/api/library-elements/cpu-usage": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusNotFound,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"library element could not be found"}`)),
+					}
+				},
+				"http://somegrafana.com/api/library-elements": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(
+							strings.NewReader(
+								`{"result":{"id":1,"uid":"cpu-usage","name":"CPU usage","kind":1,"version":1}}`,
+							),
+						),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewLibraryPanelReconciler(fakeStore{}, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	libraryPanel := dawgv1.LibraryPanel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cpu-usage",
+			Namespace: "default",
+		},
+		Spec: dawgv1.LibraryPanelSpec{
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{
+					Raw: []byte(`{"uid":"cpu-usage","name":"CPU usage","model":{"type":"timeseries","title":"CPU usage"}}`),
+				},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &libraryPanel)
+	require.NoError(t, err)
+
+	// This should look the library panel up, then create it.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	getRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodGet, getRequest.Method)
+
+	createRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodPost, createRequest.Method)
+	assert.JSONEq(
+		t,
+		`{"uid":"cpu-usage","name":"CPU usage","model":{"type":"timeseries","title":"CPU usage"},"kind":1}`,
+		readAll(t, grafanaBackend.readRequestBody(t, 1)),
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&libraryPanel), &libraryPanel)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(libraryPanel.Status.Conditions, dawgv1.LibraryPanelConditionReady)
+	})
+
+	assert.Equal(t, "cpu-usage", libraryPanel.Status.UID)
+	assert.Equal(t, "CPU usage", libraryPanel.Status.Name)
+
+	err = k8sClient.Delete(ctx, &libraryPanel)
+	require.NoError(t, err)
+
+	// This should delete the library panel.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	deleteRequest := grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodDelete, deleteRequest.Method)
+	assert.Equal(t, "/api/library-elements/cpu-usage", deleteRequest.URL.Path)
+}
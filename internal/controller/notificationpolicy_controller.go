@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/gdk"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+const notificationPolicyFinalizer = "notificationpolicy.dawg.urcloud.cc/finalizer"
+
+var errInvalidPolicyTree = errors.New("policy tree must have a receiver")
+
+// NotificationPolicyReconciler reconciles a NotificationPolicy object.
+// A policy replaces the whole policy tree of a Grafana organization: when several policies target the same server
+// and organization, even through different GrafanaInstances, the oldest one is applied and the others are rejected.
+type NotificationPolicyReconciler struct {
+	options
+	statusReporter
+
+	// apiReader reads secrets straight from the API server, to avoid caching all the secrets of the cluster.
+	apiReader      client.Reader
+	generatorStore generator.Reader
+	runtime        generator.Runtime
+	// grafana is the Grafana of the controller, nil if policies must reference a GrafanaInstance.
+	grafana   *grafana.Client
+	instances *grafanaPool
+}
+
+func NewNotificationPolicyReconciler(store generator.Reader, runtime generator.Runtime, grafana *grafana.Client, opts ...Option) *NotificationPolicyReconciler {
+	options := newOptions(opts)
+
+	return &NotificationPolicyReconciler{
+		options:        options,
+		generatorStore: store,
+		runtime:        runtime,
+		grafana:        grafana,
+		instances:      newGrafanaPool(options.grafanaClientOpts),
+	}
+}
+
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=notificationpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=notificationpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dawg.urcloud.cc,resources=notificationpolicies/finalizers,verbs=update
+
+// Reconcile handles notification policy reconciliation.
+func (r *NotificationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var policy dawgv1.NotificationPolicy
+
+	if err := r.k8sClient.Get(ctx, req.NamespacedName, &policy); err != nil {
+		logger.Error(err, "Could not fetch the notification policy")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !policy.DeletionTimestamp.IsZero() {
+		return r.deletePolicy(ctx, &policy, logger)
+	}
+
+	return r.applyPolicy(ctx, &policy, logger)
+}
+
+func (r *NotificationPolicyReconciler) applyPolicy(ctx context.Context, policy *dawgv1.NotificationPolicy, logger logr.Logger) (ctrl.Result, error) {
+	logger = logger.WithValues("generator", policy.Spec.Generator, "grafana_instance", policy.Spec.GrafanaInstance)
+
+	logger.Info("Applying notification policy")
+
+	if !controllerutil.ContainsFinalizer(policy, notificationPolicyFinalizer) {
+		controllerutil.AddFinalizer(policy, notificationPolicyFinalizer)
+		if err := r.k8sClient.Update(ctx, policy); err != nil {
+			r.setFailureStatus(ctx, policy, reasonFinalizerFailed, "Could set finalizer", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	var policies dawgv1.NotificationPolicyList
+	if err := r.k8sClient.List(ctx, &policies); err != nil {
+		r.setFailureStatus(ctx, policy, reasonConflict, "Could not list the notification policies", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	target := r.policyTarget(ctx, policy.Namespace, policy.Spec.GrafanaInstance)
+
+	if winner := r.policyOwner(ctx, policies.Items, target); winner != nil &&
+		client.ObjectKeyFromObject(winner) != client.ObjectKeyFromObject(policy) {
+		// The tree of the policy is not applied anymore, it must not be reset when the policy is deleted.
+		policy.Status.Receiver = ""
+		policy.Status.GrafanaInstance = ""
+
+		r.setFailureStatus(
+			ctx,
+			policy,
+			reasonConflict,
+			"Another notification policy targets the same Grafana server and organization",
+			fmt.Errorf("policy tree is owned by NotificationPolicy %s", client.ObjectKeyFromObject(winner)),
+			logger,
+		)
+
+		// The policy is reconciled again once the owner is deleted.
+		return ctrl.Result{}, nil
+	}
+
+	payload, digest, runErr := r.sourcePayload(
+		ctx,
+		r.apiReader,
+		r.generatorStore,
+		r.runtime,
+		resourceContext("NotificationPolicy", policy),
+		policy.Spec.PayloadSource,
+		gdk.ResourceKindNotificationPolicy,
+		logger,
+	)
+	if digest != "" {
+		policy.Status.GeneratorDigest = digest
+	}
+
+	if runErr != nil {
+		r.setFailureStatus(ctx, policy, runErr.reason, runErr.message, runErr.err, logger)
+
+		if !runErr.retry {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, runErr.err
+	}
+
+	var tree grafana.NotificationPolicyTree
+	if err := json.Unmarshal(payload, &tree); err != nil {
+		r.setFailureStatus(ctx, policy, reasonInvalidOutput, "Policy tree is invalid", err, logger)
+		return ctrl.Result{}, nil
+	}
+
+	if tree.Receiver == "" {
+		r.setFailureStatus(ctx, policy, reasonInvalidOutput, "Policy tree is invalid", errInvalidPolicyTree, logger)
+		return ctrl.Result{}, nil
+	}
+
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: policy.Namespace, Name: policy.Spec.GrafanaInstance},
+	)
+	if err != nil {
+		r.setFailureStatus(ctx, policy, reasonInstancesFailed, "Could not resolve the Grafana instance of the notification policy", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	// Reset the tree of the previous instance, unless this policy or another one still targets its server.
+	if previous := policy.Status; previous.Receiver != "" && previous.GrafanaInstance != policy.Spec.GrafanaInstance &&
+		r.policyOwner(ctx, policies.Items, r.policyTarget(ctx, policy.Namespace, previous.GrafanaInstance)) == nil {
+		if err := r.resetGrafana(ctx, policy); err != nil {
+			r.setFailureStatus(ctx, policy, reasonGrafanaFailed, "Could not reset the policy tree of the previous Grafana instance", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := cl.PutNotificationPolicyTree(ctx, &grafana.PutNotificationPolicyTreeRequest{Tree: payload}); err != nil {
+		r.setFailureStatus(ctx, policy, reasonGrafanaFailed, "Could not update the policy tree in Grafana", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	policy.Status.Receiver = tree.Receiver
+	policy.Status.GrafanaInstance = policy.Spec.GrafanaInstance
+
+	r.setStatus(ctx, policy, metav1.ConditionTrue, reasonReady, "Notification policy is ready", logger)
+
+	logger.Info("Applied notification policy", "routes", len(tree.Routes))
+
+	return ctrl.Result{}, nil
+}
+
+// policyTarget identifies the Grafana server and organization whose policy tree a policy replaces.
+// If the instance cannot be resolved, the policy only competes with the policies referencing the same instance.
+func (r *NotificationPolicyReconciler) policyTarget(ctx context.Context, namespace, instance string) string {
+	server, err := grafanaServer(ctx, r.k8sClient, r.grafana, types.NamespacedName{Namespace: namespace, Name: instance})
+	if err != nil {
+		return "instance:" + namespace + "/" + instance
+	}
+
+	return server
+}
+
+// policyOwner returns the policy applied to a target: the oldest one targeting it, deleted policies included until they are gone.
+func (r *NotificationPolicyReconciler) policyOwner(ctx context.Context, policies []dawgv1.NotificationPolicy, target string) *dawgv1.NotificationPolicy {
+	var owner *dawgv1.NotificationPolicy
+
+	for i := range policies {
+		policy := &policies[i]
+
+		if r.policyTarget(ctx, policy.Namespace, policy.Spec.GrafanaInstance) != target {
+			continue
+		}
+
+		if owner == nil || olderPolicy(policy, owner) {
+			owner = policy
+		}
+	}
+
+	return owner
+}
+
+func olderPolicy(a, b *dawgv1.NotificationPolicy) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+func (r *NotificationPolicyReconciler) deletePolicy(ctx context.Context, policy *dawgv1.NotificationPolicy, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(policy, notificationPolicyFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if policy.Status.Receiver == "" {
+		logger.Info("Deleting a notification policy not applied, not resetting the Grafana policy tree")
+	} else {
+		logger.Info("Deleting notification policy")
+
+		if err := r.resetGrafana(ctx, policy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(policy, notificationPolicyFinalizer)
+	if err := r.k8sClient.Update(ctx, policy); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted notification policy")
+
+	return ctrl.Result{}, nil
+}
+
+// resetGrafana restores the default policy tree of the instance the policy was applied to.
+// There is nothing to reset if the GrafanaInstance is gone.
+func (r *NotificationPolicyReconciler) resetGrafana(ctx context.Context, policy *dawgv1.NotificationPolicy) error {
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: policy.Namespace, Name: policy.Status.GrafanaInstance},
+	)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	return cl.ResetNotificationPolicyTree(ctx)
+}
+
+// competingPolicies enqueues the policies targeting the instances a policy targets, or was applied to.
+func (r *NotificationPolicyReconciler) competingPolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	changed, ok := obj.(*dawgv1.NotificationPolicy)
+	if !ok {
+		return nil
+	}
+
+	var policies dawgv1.NotificationPolicyList
+
+	if err := r.k8sClient.List(ctx, &policies); err != nil {
+		log.FromContext(ctx).Error(err, "Could not list the notification policies")
+		return nil
+	}
+
+	targets := map[string]bool{
+		r.policyTarget(ctx, changed.Namespace, changed.Spec.GrafanaInstance):   true,
+		r.policyTarget(ctx, changed.Namespace, changed.Status.GrafanaInstance): true,
+	}
+
+	var requests []reconcile.Request
+
+	for _, policy := range policies.Items {
+		if client.ObjectKeyFromObject(&policy) == client.ObjectKeyFromObject(changed) {
+			continue
+		}
+
+		if targets[r.policyTarget(ctx, policy.Namespace, policy.Spec.GrafanaInstance)] {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&policy)})
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NotificationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.k8sClient = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(
+			&dawgv1.NotificationPolicy{},
+			// Do not process status updates, nor delete events as we're using finalizers.
+			builder.WithPredicates(
+				predicate.GenerationChangedPredicate{},
+				predicate.Funcs{
+					DeleteFunc: func(e event.DeleteEvent) bool { return false },
+				},
+			),
+		).
+		// Policies rejected because of a conflict are reconciled again when the conflicting policies change or go away.
+		Watches(
+			&dawgv1.NotificationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.competingPolicies),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
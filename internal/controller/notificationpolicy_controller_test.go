@@ -0,0 +1,251 @@
+package controller_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/internal/controller"
+	"github.com/jlevesy/dawg/pkg/grafana"
+	"github.com/jlevesy/dawg/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestNotificationPolicyController_RejectsConflictingPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/v1/provisioning/policies": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusAccepted,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"policies updated"}`)),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewNotificationPolicyReconciler(fakeStore{}, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	ownerPolicy := dawgv1.NotificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-policy",
+			Namespace: "default",
+		},
+		Spec: dawgv1.NotificationPolicySpec{
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{Raw: []byte(`{"receiver":"oncall","routes":[{"receiver":"team-a"}]}`)},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &ownerPolicy)
+	require.NoError(t, err)
+
+	// This should apply the policy tree.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	putRequest := grafanaBackend.readRequest(t, 0)
+	assert.Equal(t, http.MethodPut, putRequest.Method)
+	assert.JSONEq(
+		t,
+		`{"receiver":"oncall","routes":[{"receiver":"team-a"}]}`,
+		readAll(t, grafanaBackend.readRequestBody(t, 0)),
+	)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&ownerPolicy), &ownerPolicy)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(ownerPolicy.Status.Conditions, dawgv1.AlertingConditionReady)
+	})
+
+	assert.Equal(t, "oncall", ownerPolicy.Status.Receiver)
+
+	conflictingPolicy := dawgv1.NotificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "b-policy",
+			Namespace: "default",
+		},
+		Spec: dawgv1.NotificationPolicySpec{
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{Raw: []byte(`{"receiver":"team-b"}`)},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &conflictingPolicy)
+	require.NoError(t, err)
+
+	// The conflicting policy is rejected without touching Grafana.
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&conflictingPolicy), &conflictingPolicy)
+		require.NoError(t, err)
+
+		cond := meta.FindStatusCondition(conflictingPolicy.Status.Conditions, dawgv1.AlertingConditionReady)
+		return cond != nil && cond.Reason == "Conflict"
+	})
+
+	assert.Empty(t, conflictingPolicy.Status.Receiver)
+
+	err = k8sClient.Delete(ctx, &ownerPolicy)
+	require.NoError(t, err)
+
+	// This should reset the policy tree, then apply the tree of the remaining policy.
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	resetRequest := grafanaBackend.readRequest(t, 1)
+	assert.Equal(t, http.MethodDelete, resetRequest.Method)
+
+	putRequest = grafanaBackend.readRequest(t, 2)
+	assert.Equal(t, http.MethodPut, putRequest.Method)
+	assert.JSONEq(t, `{"receiver":"team-b"}`, readAll(t, grafanaBackend.readRequestBody(t, 2)))
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&conflictingPolicy), &conflictingPolicy)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(conflictingPolicy.Status.Conditions, dawgv1.AlertingConditionReady)
+	})
+}
+
+func TestNotificationPolicyController_RejectsPoliciesTargetingTheSameServer(t *testing.T) {
+	ctx := context.Background()
+
+	k8sCluster := testutil.RunContainer(t, testutil.KWOKContainerConfig)
+	t.Cleanup(func() {
+		require.NoError(t, k8sCluster.Shutdown(ctx))
+	})
+
+	genRuntime, shutdown, err := generator.DefaultRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, shutdown(ctx))
+	})
+
+	var (
+		grafanaBackend = stubRoundtripper{
+			reqReceived: make(chan struct{}),
+			resps: map[string]func() *http.Response{
+				"http://somegrafana.com/api/v1/provisioning/policies": func() *http.Response {
+					return &http.Response{
+						StatusCode: http.StatusAccepted,
+						Body:       io.NopCloser(strings.NewReader(`{"message":"policies updated"}`)),
+					}
+				},
+			},
+		}
+
+		grafanaClient = grafana.NewClient(
+			"http://somegrafana.com",
+			grafana.WithRoundTripper(&grafanaBackend),
+		)
+		mgr = testutil.NewTestingManager(
+			t,
+			&rest.Config{Host: "http://localhost:" + k8sCluster.Port},
+			controller.NewNotificationPolicyReconciler(fakeStore{}, genRuntime, grafanaClient),
+		)
+		k8sClient = mgr.GetClient()
+	)
+
+	ownerPolicy := dawgv1.NotificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-policy",
+			Namespace: "default",
+		},
+		Spec: dawgv1.NotificationPolicySpec{
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{Raw: []byte(`{"receiver":"oncall"}`)},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &ownerPolicy)
+	require.NoError(t, err)
+
+	testutil.WaitForSignal(t, time.Second, grafanaBackend.reqReceived)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&ownerPolicy), &ownerPolicy)
+		require.NoError(t, err)
+		return meta.IsStatusConditionTrue(ownerPolicy.Status.Conditions, dawgv1.AlertingConditionReady)
+	})
+
+	// Another spelling of the URL of the Grafana of the controller.
+	err = k8sClient.Create(ctx, &dawgv1.GrafanaInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "same-grafana", Namespace: "default"},
+		Spec: dawgv1.GrafanaInstanceSpec{
+			URL: "HTTP://SomeGrafana.com:80/",
+			TokenSecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "grafana-tokens"},
+				Key:                  "token",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	conflictingPolicy := dawgv1.NotificationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "b-policy",
+			Namespace: "default",
+		},
+		Spec: dawgv1.NotificationPolicySpec{
+			GrafanaInstance: "same-grafana",
+			PayloadSource: dawgv1.PayloadSource{
+				Payload: &runtime.RawExtension{Raw: []byte(`{"receiver":"team-b"}`)},
+			},
+		},
+	}
+
+	err = k8sClient.Create(ctx, &conflictingPolicy)
+	require.NoError(t, err)
+
+	testutil.Retry(t, 10, time.Second, func() bool {
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&conflictingPolicy), &conflictingPolicy)
+		require.NoError(t, err)
+
+		cond := meta.FindStatusCondition(conflictingPolicy.Status.Conditions, dawgv1.AlertingConditionReady)
+		return cond != nil && cond.Reason == "Conflict"
+	})
+
+	assert.Empty(t, conflictingPolicy.Status.Receiver)
+
+	// Only the tree of the owner policy was applied.
+	grafanaBackend.mu.Lock()
+	defer grafanaBackend.mu.Unlock()
+	assert.Len(t, grafanaBackend.reqs, 1)
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+var errGeneratorNotPinned = errors.New("generator must be referenced by digest, eg: registry://host/repository@sha256:...")
+
+// Option configures the dashboard reconciler and validator.
+type Option func(*options)
+
+type options struct {
+	defaultPullSecret       *types.NamespacedName
+	requirePinnedGenerators bool
+	resyncInterval          time.Duration
+	grafanaClientOpts       []grafana.ClientOpt
+	grafanaContext          bool
+}
+
+// WithDefaultPullSecret configures a secret holding registry credentials used for all dashboards,
+// when their own image pull secrets do not provide credentials for the generator registry.
+func WithDefaultPullSecret(ref types.NamespacedName) Option {
+	return func(opts *options) {
+		opts.defaultPullSecret = &ref
+	}
+}
+
+// WithRequirePinnedGenerators refuses dashboards referencing a registry generator by a mutable tag,
+// they must reference it by digest instead.
+func WithRequirePinnedGenerators() Option {
+	return func(opts *options) {
+		opts.requirePinnedGenerators = true
+	}
+}
+
+// WithResyncInterval periodically reconciles applied dashboards, to detect and revert changes made to them in Grafana.
+// Dashboards are not resynced if the interval is 0.
+func WithResyncInterval(interval time.Duration) Option {
+	return func(opts *options) {
+		opts.resyncInterval = interval
+	}
+}
+
+// WithGrafanaClientOptions configures the clients of the GrafanaInstances, on top of their URL, org and token.
+func WithGrafanaClientOptions(opts ...grafana.ClientOpt) Option {
+	return func(o *options) {
+		o.grafanaClientOpts = append(o.grafanaClientOpts, opts...)
+	}
+}
+
+// WithGrafanaContext gives dashboard generators the version and the datasources of the Grafana the dashboard is applied to,
+// in their context file.
+func WithGrafanaContext() Option {
+	return func(o *options) {
+		o.grafanaContext = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// checkPinned enforces the pinned generators policy, if enabled.
+func (o options) checkPinned(generatorURL *url.URL) error {
+	if o.requirePinnedGenerators && !generator.IsPinned(generatorURL) {
+		return errGeneratorNotPinned
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jlevesy/dawg/generator"
+)
+
+// registryCredentials resolves the registry credentials of a resource from its image pull secrets, then from the default pull secret.
+func (o options) registryCredentials(ctx context.Context, reader client.Reader, namespace string, pullSecrets []corev1.LocalObjectReference) (generator.CredentialFunc, error) {
+	var credentials []generator.CredentialFunc
+
+	for _, ref := range pullSecrets {
+		cfg, err := loadPullSecret(ctx, reader, types.NamespacedName{Namespace: namespace, Name: ref.Name})
+		if err != nil {
+			return nil, err
+		}
+
+		credentials = append(credentials, cfg.Credential)
+	}
+
+	if o.defaultPullSecret != nil {
+		cfg, err := loadPullSecret(ctx, reader, *o.defaultPullSecret)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials = append(credentials, cfg.Credential)
+	}
+
+	return generator.ChainCredentials(credentials...), nil
+}
+
+func loadPullSecret(ctx context.Context, reader client.Reader, key types.NamespacedName) (*generator.DockerConfig, error) {
+	var (
+		secret corev1.Secret
+		cfg    *generator.DockerConfig
+		err    error
+	)
+
+	if err := reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("could not get pull secret %s: %w", key, err)
+	}
+
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		cfg, err = generator.ParseDockerConfig(secret.Data[corev1.DockerConfigJsonKey])
+	case corev1.SecretTypeDockercfg:
+		cfg, err = generator.ParseLegacyDockerConfig(secret.Data[corev1.DockerConfigKey])
+	default:
+		return nil, fmt.Errorf("pull secret %s has unsupported type %q", key, secret.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull secret %s: %w", key, err)
+	}
+
+	// Never run credential helpers on behalf of a secret, only inline credentials are allowed.
+	cfg.CredsStore = ""
+	cfg.CredHelpers = nil
+
+	return cfg, nil
+}
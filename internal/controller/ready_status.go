@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/go-logr/logr"
+)
+
+// conditionReady is the condition reporting if a resource other than a dashboard is applied to Grafana.
+const conditionReady = "Ready"
+
+// readyObject is a resource reporting its reconciliation with a Ready condition.
+type readyObject interface {
+	client.Object
+
+	StatusConditions() *[]metav1.Condition
+	SetObservedGeneration(generation int64)
+}
+
+// statusReporter reports the reconciliation of resources on their Ready condition and with events.
+type statusReporter struct {
+	k8sClient client.Client
+	recorder  record.EventRecorder
+}
+
+func (s *statusReporter) setFailureStatus(ctx context.Context, obj readyObject, reason, message string, err error, logger logr.Logger) {
+	logger.Error(err, message)
+
+	conditionMessage := message + ": " + err.Error()
+
+	s.recorder.Event(obj, corev1.EventTypeWarning, reason, conditionMessage)
+	s.setStatus(ctx, obj, metav1.ConditionFalse, reason, conditionMessage, logger)
+}
+
+func (s *statusReporter) setStatus(ctx context.Context, obj readyObject, status metav1.ConditionStatus, reason, message string, logger logr.Logger) {
+	obj.SetObservedGeneration(obj.GetGeneration())
+
+	changed := meta.SetStatusCondition(obj.StatusConditions(), metav1.Condition{
+		Type:               conditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: obj.GetGeneration(),
+	})
+
+	if changed && status == metav1.ConditionTrue {
+		s.recorder.Event(obj, corev1.EventTypeNormal, reason, message)
+	}
+
+	if err := s.k8sClient.Status().Update(ctx, obj); err != nil {
+		logger.Error(err, "Could not update status")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() uint64 {
+	genCtx, err := gdk.ReadContext()
+	if err != nil {
+		return gdk.Error(err)
+	}
+
+	datasource, ok := genCtx.Datasource("prometheus")
+	if !ok {
+		return gdk.Error(errors.New("no prometheus datasource"))
+	}
+
+	out, err := json.Marshal(map[string]string{"datasource": datasource.UID})
+	if err != nil {
+		return gdk.Error(err)
+	}
+
+	return gdk.WriteOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
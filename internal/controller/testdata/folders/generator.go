@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() {
+	out, err := gdk.MarshalEnvelope(
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindFolder,
+			Payload: []byte(`{"uid":"generated-folder","title":"Generated"}`),
+		},
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindLibraryPanel,
+			Payload: []byte(`{"uid":"generated-panel","name":"panel","folderUid":"generated-folder","model":{"type":"text"}}`),
+		},
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindDashboard,
+			Payload: []byte(`{"version":"folders"}`),
+		},
+	)
+	if err != nil {
+		gdk.SetError(err)
+		return
+	}
+
+	gdk.SetOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() {
+	out, err := gdk.MarshalEnvelope(
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindDashboard,
+			Payload: []byte(`{"version":"multi"}`),
+		},
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindAlertRule,
+			Payload: []byte(`{"uid":"rule-uid","title":"rule"}`),
+		},
+	)
+	if err != nil {
+		gdk.SetError(err)
+		return
+	}
+
+	gdk.SetOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/jlevesy/dawg/gdk"
+)
+
+//export generate
+func generate() {
+	out, err := gdk.MarshalEnvelope(
+		gdk.EnvelopeResource{
+			Kind:    gdk.ResourceKindAlertRuleGroup,
+			Payload: []byte(`{"title":"rules","folderUid":"alerts-folder","interval":60,"rules":[{"uid":"rule-uid","title":"rule"}]}`),
+		},
+	)
+	if err != nil {
+		gdk.SetError(err)
+		return
+	}
+
+	gdk.SetOutput(out)
+}
+
+// main is required for the `wasi` target, even if it isn't used.
+// See https://wazero.io/languages/tinygo/#why-do-i-have-to-define-main
+func main() {}
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	dawgv1 "github.com/jlevesy/dawg/api/v1"
+	"github.com/jlevesy/dawg/generator"
+	"github.com/jlevesy/dawg/pkg/grafana"
+)
+
+// uidResourceStatus points to the status fields of a resource identified in Grafana by its UID.
+type uidResourceStatus struct {
+	uid             *string
+	grafanaInstance *string
+	generatorDigest *string
+}
+
+// uidResourceHandler provisions a kind of Grafana resource identified by its UID, from an inline or generated payload.
+// T is the Kubernetes resource, D the decoded Grafana resource.
+type uidResourceHandler[T readyObject, D any] struct {
+	// name of the resource in logs and conditions, eg: "contact point".
+	name string
+	// kind of the Kubernetes resource, given to generators in their context.
+	kind string
+	// outputKind is the resource kind expected from generators.
+	outputKind string
+	finalizer  string
+
+	newObject func() T
+	// spec returns the payload source of a resource and the GrafanaInstance it targets.
+	spec func(obj T) (dawgv1.PayloadSource, string)
+	// status returns the status fields recording what was applied.
+	status func(obj T) uidResourceStatus
+	// decode validates a payload and sets its UID if it has none, it returns the payload, the decoded resource and its UID.
+	decode func(payload json.RawMessage, uid string) (json.RawMessage, D, string, error)
+	apply  func(ctx context.Context, cl *grafana.Client, uid string, payload json.RawMessage) error
+	delete func(ctx context.Context, cl *grafana.Client, uid string) error
+	// applied records the kind specific fields of the applied resource in the status.
+	applied func(obj T, desired D)
+}
+
+// uidResourceReconciler reconciles the resources of a uidResourceHandler.
+type uidResourceReconciler[T readyObject, D any] struct {
+	options
+	statusReporter
+
+	// apiReader reads secrets straight from the API server, to avoid caching all the secrets of the cluster.
+	apiReader      client.Reader
+	generatorStore generator.Reader
+	runtime        generator.Runtime
+	// grafana is the Grafana of the controller, nil if the resources must reference a GrafanaInstance.
+	grafana   *grafana.Client
+	instances *grafanaPool
+
+	handler uidResourceHandler[T, D]
+}
+
+func newUIDResourceReconciler[T readyObject, D any](
+	handler uidResourceHandler[T, D],
+	store generator.Reader,
+	runtime generator.Runtime,
+	grafana *grafana.Client,
+	opts []Option,
+) uidResourceReconciler[T, D] {
+	options := newOptions(opts)
+
+	return uidResourceReconciler[T, D]{
+		options:        options,
+		generatorStore: store,
+		runtime:        runtime,
+		grafana:        grafana,
+		instances:      newGrafanaPool(options.grafanaClientOpts),
+		handler:        handler,
+	}
+}
+
+// Reconcile applies the resource to Grafana, or deletes it from Grafana.
+func (r *uidResourceReconciler[T, D]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	obj := r.handler.newObject()
+
+	if err := r.k8sClient.Get(ctx, req.NamespacedName, obj); err != nil {
+		logger.Error(err, "Could not fetch the "+r.handler.name)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return r.delete(ctx, obj, logger)
+	}
+
+	return r.apply(ctx, obj, logger)
+}
+
+func (r *uidResourceReconciler[T, D]) apply(ctx context.Context, obj T, logger logr.Logger) (ctrl.Result, error) {
+	source, instance := r.handler.spec(obj)
+	status := r.handler.status(obj)
+
+	logger = logger.WithValues("generator", source.Generator, "grafana_instance", instance)
+
+	logger.Info("Applying " + r.handler.name)
+
+	if !controllerutil.ContainsFinalizer(obj, r.handler.finalizer) {
+		controllerutil.AddFinalizer(obj, r.handler.finalizer)
+		if err := r.k8sClient.Update(ctx, obj); err != nil {
+			r.setFailureStatus(ctx, obj, reasonFinalizerFailed, "Could set finalizer", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	payload, digest, runErr := r.sourcePayload(
+		ctx,
+		r.apiReader,
+		r.generatorStore,
+		r.runtime,
+		resourceContext(r.handler.kind, obj),
+		source,
+		r.handler.outputKind,
+		logger,
+	)
+	if digest != "" {
+		*status.generatorDigest = digest
+	}
+
+	if runErr != nil {
+		r.setFailureStatus(ctx, obj, runErr.reason, runErr.message, runErr.err, logger)
+
+		if !runErr.retry {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, runErr.err
+	}
+
+	// Keep the UID of the applied resource, or derive one from the Kubernetes resource, if the payload does not set one.
+	uid := *status.uid
+	if uid == "" {
+		uid = string(obj.GetUID())
+	}
+
+	payload, desired, uid, err := r.handler.decode(payload, uid)
+	if err != nil {
+		r.setFailureStatus(ctx, obj, reasonInvalidOutput, capitalize(r.handler.name)+" is invalid", err, logger)
+		// The payload won't change until the resource does.
+		return ctrl.Result{}, nil
+	}
+
+	logger = logger.WithValues("uid", uid)
+
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: obj.GetNamespace(), Name: instance},
+	)
+	if err != nil {
+		r.setFailureStatus(ctx, obj, reasonInstancesFailed, "Could not resolve the Grafana instance of the "+r.handler.name, err, logger)
+		return ctrl.Result{}, err
+	}
+
+	// The resource is identified by its UID and instance, if one of them changes the previous resource must go.
+	if *status.uid != "" && (*status.uid != uid || *status.grafanaInstance != instance) {
+		if err := r.deleteFromGrafana(ctx, obj); err != nil {
+			r.setFailureStatus(ctx, obj, reasonGrafanaFailed, "Could not delete the previous "+r.handler.name+" from Grafana", err, logger)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.handler.apply(ctx, cl, uid, payload); err != nil {
+		r.setFailureStatus(ctx, obj, reasonGrafanaFailed, "Could not create or update the "+r.handler.name+" in Grafana", err, logger)
+		return ctrl.Result{}, err
+	}
+
+	*status.uid = uid
+	*status.grafanaInstance = instance
+	r.handler.applied(obj, desired)
+
+	r.setStatus(ctx, obj, metav1.ConditionTrue, reasonReady, capitalize(r.handler.name)+" is ready", logger)
+
+	logger.Info("Applied " + r.handler.name)
+
+	return ctrl.Result{}, nil
+}
+
+func (r *uidResourceReconciler[T, D]) delete(ctx context.Context, obj T, logger logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(obj, r.handler.finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if *r.handler.status(obj).uid == "" {
+		logger.Info("Deleting a " + r.handler.name + " never applied, not deleting it from Grafana")
+	} else {
+		logger.Info("Deleting " + r.handler.name)
+
+		if err := r.deleteFromGrafana(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(obj, r.handler.finalizer)
+	if err := r.k8sClient.Update(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Deleted " + r.handler.name)
+
+	return ctrl.Result{}, nil
+}
+
+// deleteFromGrafana deletes the applied resource. There is nothing to delete if its GrafanaInstance is gone.
+func (r *uidResourceReconciler[T, D]) deleteFromGrafana(ctx context.Context, obj T) error {
+	status := r.handler.status(obj)
+
+	cl, err := instanceClient(
+		ctx,
+		r.k8sClient,
+		r.apiReader,
+		r.instances,
+		r.grafana,
+		types.NamespacedName{Namespace: obj.GetNamespace(), Name: *status.grafanaInstance},
+	)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	err = r.handler.delete(ctx, cl, *status.uid)
+	if err != nil && !grafana.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *uidResourceReconciler[T, D]) SetupWithManager(mgr ctrl.Manager) error {
+	r.k8sClient = mgr.GetClient()
+	r.apiReader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorderFor("dawg-controller")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(r.handler.newObject()).
+		// Do not process status updates.
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		// Do not process delete events as we're using finalizers.
+		WithEventFilter(predicate.Funcs{
+			DeleteFunc: func(e event.DeleteEvent) bool { return false },
+		}).
+		Complete(r)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
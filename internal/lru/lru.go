@@ -0,0 +1,91 @@
+// Package lru implements a cache keeping the most recently used values.
+package lru
+
+import "container/list"
+
+// Cache keeps at most a given number of values, evicting the least recently used ones first.
+// It is not safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	maxEntries int
+	ll         *list.List
+	entries    map[K]*list.Element
+	onEvict    func(key K, value V)
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// New returns a cache holding at most maxEntries values.
+// onEvict, if not nil, is called with each value leaving the cache.
+func New[K comparable, V any](maxEntries int, onEvict func(key K, value V)) *Cache[K, V] {
+	return &Cache[K, V]{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[K]*list.Element),
+		onEvict:    onEvict,
+	}
+}
+
+// Get returns the value stored for the given key, and marks it as the most recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	c.ll.MoveToFront(elem)
+
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// Add stores a value as the most recently used, replacing and evicting the one stored for the same key.
+// The least recently used values are evicted if the cache is full.
+func (c *Cache[K, V]) Add(key K, value V) {
+	if elem, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(elem)
+
+		ent := elem.Value.(*entry[K, V])
+		previous := ent.value
+		ent.value = value
+
+		c.evicted(key, previous)
+
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value})
+
+	for c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Len returns the number of values in the cache.
+func (c *Cache[K, V]) Len() int {
+	return c.ll.Len()
+}
+
+// Purge evicts all the values of the cache.
+func (c *Cache[K, V]) Purge() {
+	for c.ll.Len() > 0 {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	ent := elem.Value.(*entry[K, V])
+
+	c.ll.Remove(elem)
+	delete(c.entries, ent.key)
+
+	c.evicted(ent.key, ent.value)
+}
+
+func (c *Cache[K, V]) evicted(key K, value V) {
+	if c.onEvict != nil {
+		c.onEvict(key, value)
+	}
+}
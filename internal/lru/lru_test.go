@@ -0,0 +1,70 @@
+package lru_test
+
+import (
+	"testing"
+
+	"github.com/jlevesy/dawg/internal/lru"
+	"github.com/stretchr/testify/assert"
+)
+
+type eviction struct {
+	key   string
+	value int
+}
+
+func TestCache(t *testing.T) {
+	var evicted []eviction
+
+	cache := lru.New(2, func(key string, value int) {
+		evicted = append(evicted, eviction{key: key, value: value})
+	})
+
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+
+	// Using a marks b as the least recently used.
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.Add("c", 3)
+
+	assert.Equal(t, []eviction{{key: "b", value: 2}}, evicted)
+	assert.Equal(t, 2, cache.Len())
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+
+	// Replacing a value evicts the previous one, without evicting other keys.
+	cache.Add("a", 10)
+
+	assert.Equal(t, []eviction{{key: "b", value: 2}, {key: "a", value: 1}}, evicted)
+	assert.Equal(t, 2, cache.Len())
+
+	value, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 10, value)
+
+	cache.Purge()
+
+	assert.Equal(
+		t,
+		[]eviction{{key: "b", value: 2}, {key: "a", value: 1}, {key: "c", value: 3}, {key: "a", value: 10}},
+		evicted,
+	)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCache_NoEvictionHook(t *testing.T) {
+	cache := lru.New[string, int](1, nil)
+
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+
+	value, ok := cache.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
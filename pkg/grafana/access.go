@@ -0,0 +1,56 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const (
+	searchTeamsEndpoint = "/api/teams/search"
+	lookupUserEndpoint  = "/api/users/lookup"
+)
+
+type Team struct {
+	ID    int    `json:"id"`
+	UID   string `json:"uid"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type SearchTeamsRequest struct {
+	Name string
+}
+
+type SearchTeamsResponse struct {
+	TotalCount int    `json:"totalCount"`
+	Teams      []Team `json:"teams"`
+}
+
+func (c *Client) SearchTeams(ctx context.Context, req *SearchTeamsRequest) (*SearchTeamsResponse, error) {
+	var resp SearchTeamsResponse
+
+	query := url.Values{"name": []string{req.Name}}
+
+	return &resp, c.do(ctx, http.MethodGet, searchTeamsEndpoint+"?"+query.Encode(), nil, &resp)
+}
+
+type User struct {
+	ID    int    `json:"id"`
+	UID   string `json:"uid"`
+	Login string `json:"login"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type LookupUserRequest struct {
+	LoginOrEmail string
+}
+
+func (c *Client) LookupUser(ctx context.Context, req *LookupUserRequest) (*User, error) {
+	var resp User
+
+	query := url.Values{"loginOrEmail": []string{req.LoginOrEmail}}
+
+	return &resp, c.do(ctx, http.MethodGet, lookupUserEndpoint+"?"+query.Encode(), nil, &resp)
+}
@@ -0,0 +1,175 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+const alertRulesEndpoint = "/api/v1/provisioning/alert-rules"
+
+type AlertRule struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	FolderUID string `json:"folderUID"`
+	RuleGroup string `json:"ruleGroup"`
+}
+
+type CreateAlertRuleRequest struct {
+	Rule json.RawMessage
+}
+
+func (c *Client) CreateAlertRule(ctx context.Context, req *CreateAlertRuleRequest) (*AlertRule, error) {
+	var resp AlertRule
+
+	return &resp, c.do(ctx, http.MethodPost, alertRulesEndpoint, req.Rule, &resp)
+}
+
+type UpdateAlertRuleRequest struct {
+	UID  string
+	Rule json.RawMessage
+}
+
+func (c *Client) UpdateAlertRule(ctx context.Context, req *UpdateAlertRuleRequest) (*AlertRule, error) {
+	var resp AlertRule
+
+	return &resp, c.do(ctx, http.MethodPut, path.Join(alertRulesEndpoint, req.UID), req.Rule, &resp)
+}
+
+type DeleteAlertRuleRequest struct {
+	UID string
+}
+
+func (c *Client) DeleteAlertRule(ctx context.Context, req *DeleteAlertRuleRequest) error {
+	return c.do(ctx, http.MethodDelete, path.Join(alertRulesEndpoint, req.UID), nil, nil)
+}
+
+const folderProvisioningEndpoint = "/api/v1/provisioning/folder"
+
+type AlertRuleGroup struct {
+	Title     string            `json:"title"`
+	FolderUID string            `json:"folderUid"`
+	Interval  int64             `json:"interval"`
+	Rules     []json.RawMessage `json:"rules"`
+}
+
+func ruleGroupPath(folderUID, title string) string {
+	return path.Join(folderProvisioningEndpoint, url.PathEscape(folderUID), "rule-groups", url.PathEscape(title))
+}
+
+type GetAlertRuleGroupRequest struct {
+	FolderUID string
+	Title     string
+}
+
+func (c *Client) GetAlertRuleGroup(ctx context.Context, req *GetAlertRuleGroupRequest) (*AlertRuleGroup, error) {
+	var resp AlertRuleGroup
+
+	return &resp, c.do(ctx, http.MethodGet, ruleGroupPath(req.FolderUID, req.Title), nil, &resp)
+}
+
+type PutAlertRuleGroupRequest struct {
+	FolderUID string
+	Title     string
+	Group     json.RawMessage
+}
+
+// PutAlertRuleGroup creates or replaces a rule group, rules of the group missing from the request are deleted.
+func (c *Client) PutAlertRuleGroup(ctx context.Context, req *PutAlertRuleGroupRequest) (*AlertRuleGroup, error) {
+	var resp AlertRuleGroup
+
+	return &resp, c.do(ctx, http.MethodPut, ruleGroupPath(req.FolderUID, req.Title), req.Group, &resp)
+}
+
+type DeleteAlertRuleGroupRequest struct {
+	FolderUID string
+	Title     string
+}
+
+func (c *Client) DeleteAlertRuleGroup(ctx context.Context, req *DeleteAlertRuleGroupRequest) error {
+	return c.do(ctx, http.MethodDelete, ruleGroupPath(req.FolderUID, req.Title), nil, nil)
+}
+
+const contactPointsEndpoint = "/api/v1/provisioning/contact-points"
+
+type ContactPoint struct {
+	UID                   string          `json:"uid"`
+	Name                  string          `json:"name"`
+	Type                  string          `json:"type"`
+	Settings              json.RawMessage `json:"settings"`
+	DisableResolveMessage bool            `json:"disableResolveMessage"`
+}
+
+type GetContactPointsRequest struct {
+	// Name filters the contact points by name, all contact points are returned if empty.
+	Name string
+}
+
+func (c *Client) GetContactPoints(ctx context.Context, req *GetContactPointsRequest) ([]ContactPoint, error) {
+	endpoint := contactPointsEndpoint
+	if req.Name != "" {
+		endpoint += "?" + url.Values{"name": []string{req.Name}}.Encode()
+	}
+
+	var resp []ContactPoint
+
+	return resp, c.do(ctx, http.MethodGet, endpoint, nil, &resp)
+}
+
+type CreateContactPointRequest struct {
+	ContactPoint json.RawMessage
+}
+
+func (c *Client) CreateContactPoint(ctx context.Context, req *CreateContactPointRequest) (*ContactPoint, error) {
+	var resp ContactPoint
+
+	return &resp, c.do(ctx, http.MethodPost, contactPointsEndpoint, req.ContactPoint, &resp)
+}
+
+type UpdateContactPointRequest struct {
+	UID          string
+	ContactPoint json.RawMessage
+}
+
+func (c *Client) UpdateContactPoint(ctx context.Context, req *UpdateContactPointRequest) error {
+	return c.do(ctx, http.MethodPut, path.Join(contactPointsEndpoint, url.PathEscape(req.UID)), req.ContactPoint, nil)
+}
+
+type DeleteContactPointRequest struct {
+	UID string
+}
+
+func (c *Client) DeleteContactPoint(ctx context.Context, req *DeleteContactPointRequest) error {
+	return c.do(ctx, http.MethodDelete, path.Join(contactPointsEndpoint, url.PathEscape(req.UID)), nil, nil)
+}
+
+const policiesEndpoint = "/api/v1/provisioning/policies"
+
+// NotificationPolicyTree is the root of the notification policies of an organization.
+type NotificationPolicyTree struct {
+	Receiver string            `json:"receiver"`
+	Routes   []json.RawMessage `json:"routes,omitempty"`
+}
+
+func (c *Client) GetNotificationPolicyTree(ctx context.Context) (json.RawMessage, error) {
+	var resp json.RawMessage
+
+	return resp, c.do(ctx, http.MethodGet, policiesEndpoint, nil, &resp)
+}
+
+type PutNotificationPolicyTreeRequest struct {
+	Tree json.RawMessage
+}
+
+// PutNotificationPolicyTree replaces the whole policy tree of the organization.
+func (c *Client) PutNotificationPolicyTree(ctx context.Context, req *PutNotificationPolicyTreeRequest) error {
+	return c.do(ctx, http.MethodPut, policiesEndpoint, req.Tree, nil)
+}
+
+// ResetNotificationPolicyTree restores the default policy tree of the organization.
+func (c *Client) ResetNotificationPolicyTree(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, policiesEndpoint, nil, nil)
+}
@@ -4,23 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 )
 
 type Client struct {
 	httpClient *http.Client
 
-	host string
+	host  string
+	orgID int64
 }
 
 type ClientOpt func(*Client)
 
 func NewClient(host string, opts ...ClientOpt) *Client {
-	cl := Client{httpClient: http.DefaultClient, host: host}
+	// Each client owns its transport, so that clients of several Grafana instances do not share credentials.
+	cl := Client{httpClient: &http.Client{}, host: host}
 
 	for _, opt := range opts {
 		opt(&cl)
@@ -29,6 +33,16 @@ func NewClient(host string, opts ...ClientOpt) *Client {
 	return &cl
 }
 
+// Host returns the URL of the Grafana server.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// OrgID returns the organization targeted by the client, 0 for the current organization of the token user.
+func (c *Client) OrgID() int64 {
+	return c.orgID
+}
+
 func WithRoundTripper(t http.RoundTripper) ClientOpt {
 	return func(cl *Client) {
 		cl.httpClient.Transport = t
@@ -60,6 +74,33 @@ func (a *authorizedRoundTripper) RoundTrip(r *http.Request) (*http.Response, err
 	return a.next.RoundTrip(r)
 }
 
+// WithOrgID targets an organization of the Grafana server, instead of the current organization of the token user.
+func WithOrgID(orgID int64) ClientOpt {
+	return func(cl *Client) {
+		cl.orgID = orgID
+
+		next := cl.httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+
+		cl.httpClient.Transport = &orgRoundTripper{
+			next:  next,
+			orgID: strconv.FormatInt(orgID, 10),
+		}
+	}
+}
+
+type orgRoundTripper struct {
+	next  http.RoundTripper
+	orgID string
+}
+
+func (o *orgRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.Header.Set("X-Grafana-Org-Id", o.orgID)
+	return o.next.RoundTrip(r)
+}
+
 type APIError struct {
 	Message    string `json:"message"`
 	MessageID  string `json:"messageId"`
@@ -95,6 +136,36 @@ func (c *Client) CreateDashboard(ctx context.Context, req *CreateDashboardReques
 	return &resp, c.do(ctx, http.MethodPost, createDashboardEndpoint, req, &resp)
 }
 
+type GetDashboardRequest struct {
+	UID string
+}
+
+type GetDashboardResponse struct {
+	Dashboard json.RawMessage `json:"dashboard"`
+	Meta      DashboardMeta   `json:"meta"`
+}
+
+type DashboardMeta struct {
+	Slug      string `json:"slug"`
+	URL       string `json:"url"`
+	FolderUID string `json:"folderUid"`
+	Version   int    `json:"version"`
+}
+
+const getDashboardEndpoint = "/api/dashboards/uid"
+
+func (c *Client) GetDashboard(ctx context.Context, req *GetDashboardRequest) (*GetDashboardResponse, error) {
+	var resp GetDashboardResponse
+
+	return &resp, c.do(
+		ctx,
+		http.MethodGet,
+		path.Join(getDashboardEndpoint, req.UID),
+		nil,
+		&resp,
+	)
+}
+
 type DeleteDashboardRequest struct {
 	UID string
 }
@@ -152,14 +223,29 @@ func (c *Client) do(ctx context.Context, method, path string, reqPayload, respPa
 		_ = resp.Body.Close()
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		var apiErr APIError
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		apiErr := APIError{StatusCode: resp.StatusCode}
 		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
-			return fmt.Errorf("could not decode response body: %w", err)
+			apiErr.Message = fmt.Sprintf("could not decode response body: %s", err)
+		}
+
+		// Grafana does not always report the status code in the body.
+		if apiErr.StatusCode == 0 {
+			apiErr.StatusCode = resp.StatusCode
 		}
 
 		return &apiErr
 	}
 
+	if respPayload == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
 	return json.NewDecoder(resp.Body).Decode(&respPayload)
 }
+
+// IsNotFound tells if the given error reports that the requested resource does not exist.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,77 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+const (
+	datasourcesEndpoint    = "/api/datasources"
+	datasourcesUIDEndpoint = "/api/datasources/uid"
+)
+
+type Datasource struct {
+	ID            int64           `json:"id,omitempty"`
+	UID           string          `json:"uid"`
+	Name          string          `json:"name"`
+	Type          string          `json:"type"`
+	Access        string          `json:"access"`
+	URL           string          `json:"url"`
+	Database      string          `json:"database,omitempty"`
+	User          string          `json:"user,omitempty"`
+	BasicAuth     bool            `json:"basicAuth"`
+	BasicAuthUser string          `json:"basicAuthUser,omitempty"`
+	IsDefault     bool            `json:"isDefault"`
+	JSONData      json.RawMessage `json:"jsonData,omitempty"`
+	Version       int             `json:"version,omitempty"`
+}
+
+func (c *Client) ListDatasources(ctx context.Context) ([]Datasource, error) {
+	var resp []Datasource
+
+	return resp, c.do(ctx, http.MethodGet, datasourcesEndpoint, nil, &resp)
+}
+
+type GetDatasourceRequest struct {
+	UID string
+}
+
+func (c *Client) GetDatasource(ctx context.Context, req *GetDatasourceRequest) (*Datasource, error) {
+	var resp Datasource
+
+	return &resp, c.do(ctx, http.MethodGet, path.Join(datasourcesUIDEndpoint, url.PathEscape(req.UID)), nil, &resp)
+}
+
+// DatasourceRequest creates or updates a datasource.
+type DatasourceRequest struct {
+	Datasource
+	// SecureJSONData holds the secrets of the datasource, Grafana encrypts them and never returns them.
+	SecureJSONData map[string]string `json:"secureJsonData,omitempty"`
+}
+
+type datasourceResponse struct {
+	Datasource Datasource `json:"datasource"`
+}
+
+func (c *Client) CreateDatasource(ctx context.Context, req *DatasourceRequest) (*Datasource, error) {
+	var resp datasourceResponse
+
+	return &resp.Datasource, c.do(ctx, http.MethodPost, datasourcesEndpoint, req, &resp)
+}
+
+func (c *Client) UpdateDatasource(ctx context.Context, req *DatasourceRequest) (*Datasource, error) {
+	var resp datasourceResponse
+
+	return &resp.Datasource, c.do(ctx, http.MethodPut, path.Join(datasourcesUIDEndpoint, url.PathEscape(req.UID)), req, &resp)
+}
+
+type DeleteDatasourceRequest struct {
+	UID string
+}
+
+func (c *Client) DeleteDatasource(ctx context.Context, req *DeleteDatasourceRequest) error {
+	return c.do(ctx, http.MethodDelete, path.Join(datasourcesUIDEndpoint, url.PathEscape(req.UID)), nil, nil)
+}
@@ -0,0 +1,110 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+const foldersEndpoint = "/api/folders"
+
+type Folder struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Version   int    `json:"version"`
+	ParentUID string `json:"parentUid,omitempty"`
+}
+
+type GetFolderRequest struct {
+	UID string
+}
+
+func (c *Client) GetFolder(ctx context.Context, req *GetFolderRequest) (*Folder, error) {
+	var resp Folder
+
+	return &resp, c.do(ctx, http.MethodGet, path.Join(foldersEndpoint, req.UID), nil, &resp)
+}
+
+type ListFoldersRequest struct {
+	// ParentUID lists the subfolders of a folder, top level folders are listed if empty.
+	ParentUID string
+}
+
+func (c *Client) ListFolders(ctx context.Context, req *ListFoldersRequest) ([]Folder, error) {
+	var (
+		resp  []Folder
+		query = url.Values{}
+	)
+
+	if req.ParentUID != "" {
+		query.Set("parentUid", req.ParentUID)
+	}
+
+	endpoint := foldersEndpoint
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	return resp, c.do(ctx, http.MethodGet, endpoint, nil, &resp)
+}
+
+type CreateFolderRequest struct {
+	UID       string `json:"uid,omitempty"`
+	Title     string `json:"title"`
+	ParentUID string `json:"parentUid,omitempty"`
+}
+
+func (c *Client) CreateFolder(ctx context.Context, req *CreateFolderRequest) (*Folder, error) {
+	var resp Folder
+
+	return &resp, c.do(ctx, http.MethodPost, foldersEndpoint, req, &resp)
+}
+
+type UpdateFolderRequest struct {
+	UID       string `json:"-"`
+	Title     string `json:"title"`
+	Version   int    `json:"version,omitempty"`
+	Overwrite bool   `json:"overwrite"`
+}
+
+func (c *Client) UpdateFolder(ctx context.Context, req *UpdateFolderRequest) (*Folder, error) {
+	var resp Folder
+
+	return &resp, c.do(ctx, http.MethodPut, path.Join(foldersEndpoint, req.UID), req, &resp)
+}
+
+type DeleteFolderRequest struct {
+	UID string
+}
+
+func (c *Client) DeleteFolder(ctx context.Context, req *DeleteFolderRequest) error {
+	return c.do(ctx, http.MethodDelete, path.Join(foldersEndpoint, req.UID), nil, nil)
+}
+
+// Folder permission levels.
+const (
+	PermissionView  = 1
+	PermissionEdit  = 2
+	PermissionAdmin = 4
+)
+
+// FolderPermission grants a permission to a role, a team or a user.
+type FolderPermission struct {
+	Role       string `json:"role,omitempty"`
+	TeamID     int    `json:"teamId,omitempty"`
+	UserID     int    `json:"userId,omitempty"`
+	Permission int    `json:"permission"`
+}
+
+type UpdateFolderPermissionsRequest struct {
+	UID   string             `json:"-"`
+	Items []FolderPermission `json:"items"`
+}
+
+// UpdateFolderPermissions replaces all the permissions of a folder.
+func (c *Client) UpdateFolderPermissions(ctx context.Context, req *UpdateFolderPermissionsRequest) error {
+	return c.do(ctx, http.MethodPost, path.Join(foldersEndpoint, req.UID, "permissions"), req, nil)
+}
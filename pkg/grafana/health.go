@@ -0,0 +1,20 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+)
+
+const healthEndpoint = "/api/health"
+
+type Health struct {
+	Version  string `json:"version"`
+	Commit   string `json:"commit"`
+	Database string `json:"database"`
+}
+
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var resp Health
+
+	return &resp, c.do(ctx, http.MethodGet, healthEndpoint, nil, &resp)
+}
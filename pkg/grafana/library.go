@@ -0,0 +1,86 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+)
+
+const (
+	libraryElementsEndpoint = "/api/library-elements"
+
+	LibraryElementKindPanel = 1
+
+	// libraryElementHasConnections is the message of Grafana refusing to delete a library element used by dashboards.
+	libraryElementHasConnections = "the library element has connections"
+)
+
+type LibraryElement struct {
+	ID        int             `json:"id"`
+	UID       string          `json:"uid"`
+	Name      string          `json:"name"`
+	Kind      int             `json:"kind"`
+	FolderUID string          `json:"folderUid"`
+	Version   int             `json:"version"`
+	Model     json.RawMessage `json:"model"`
+}
+
+type libraryElementResponse struct {
+	Result LibraryElement `json:"result"`
+}
+
+type GetLibraryElementRequest struct {
+	UID string
+}
+
+func (c *Client) GetLibraryElement(ctx context.Context, req *GetLibraryElementRequest) (*LibraryElement, error) {
+	var resp libraryElementResponse
+
+	return &resp.Result, c.do(ctx, http.MethodGet, path.Join(libraryElementsEndpoint, req.UID), nil, &resp)
+}
+
+type CreateLibraryElementRequest struct {
+	UID       string          `json:"uid,omitempty"`
+	FolderUID string          `json:"folderUid,omitempty"`
+	Name      string          `json:"name"`
+	Model     json.RawMessage `json:"model"`
+	Kind      int             `json:"kind"`
+}
+
+func (c *Client) CreateLibraryElement(ctx context.Context, req *CreateLibraryElementRequest) (*LibraryElement, error) {
+	var resp libraryElementResponse
+
+	return &resp.Result, c.do(ctx, http.MethodPost, libraryElementsEndpoint, req, &resp)
+}
+
+type UpdateLibraryElementRequest struct {
+	UID       string          `json:"uid"`
+	FolderUID string          `json:"folderUid,omitempty"`
+	Name      string          `json:"name"`
+	Model     json.RawMessage `json:"model"`
+	Kind      int             `json:"kind"`
+	// Version must match the current version of the element.
+	Version int `json:"version"`
+}
+
+func (c *Client) UpdateLibraryElement(ctx context.Context, req *UpdateLibraryElementRequest) (*LibraryElement, error) {
+	var resp libraryElementResponse
+
+	return &resp.Result, c.do(ctx, http.MethodPatch, path.Join(libraryElementsEndpoint, req.UID), req, &resp)
+}
+
+type DeleteLibraryElementRequest struct {
+	UID string
+}
+
+func (c *Client) DeleteLibraryElement(ctx context.Context, req *DeleteLibraryElementRequest) error {
+	return c.do(ctx, http.MethodDelete, path.Join(libraryElementsEndpoint, req.UID), nil, nil)
+}
+
+// IsLibraryElementInUse tells if Grafana refused to delete a library element because dashboards still use it.
+func IsLibraryElementInUse(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && apiErr.Message == libraryElementHasConnections
+}